@@ -0,0 +1,181 @@
+// Command extract-queries builds the persisted query manifest from client code. It collects
+// the GraphQL documents of .graphql and .gql files and of gql`...` and graphql`...` tagged
+// templates in JavaScript and TypeScript files, validates them against the schema, and
+// writes them with their hashes to the manifest enforced by PERSISTED_QUERY_MANIFEST.
+//
+// Usage:
+//
+//	go run ./cmd/extract-queries -out persisted-queries.json ./web/src
+//
+// Each document must hold exactly one operation, and clients must send its text exactly as
+// written, since the manifest is keyed by the hash of that text.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/persisted"
+)
+
+// taggedTemplate matches gql`...` and graphql`...` tagged templates.
+var taggedTemplate = regexp.MustCompile("(?:gql|graphql)\\s*`([^`]*)`")
+
+// documentFiles and scriptFiles are the extensions of the files searched for documents.
+var (
+	documentFiles = map[string]bool{".graphql": true, ".gql": true}
+	scriptFiles   = map[string]bool{".js": true, ".jsx": true, ".mjs": true, ".ts": true, ".tsx": true}
+)
+
+// document is a GraphQL document found in client code.
+type document struct {
+	source string
+	body   string
+}
+
+func main() {
+	out := flag.String("out", "persisted-queries.json", "manifest file to write")
+	schemaPath := flag.String("schema", "internal/schema/schema.graphql", "schema to validate operations against")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] path...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	schemaSource, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		log.Fatalf("Failed to read schema: %v", err)
+	}
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: *schemaPath, Input: string(schemaSource)})
+	if err != nil {
+		log.Fatalf("Invalid schema: %v", err)
+	}
+
+	var documents []document
+	for _, root := range flag.Args() {
+		found, err := findDocuments(root)
+		if err != nil {
+			log.Fatalf("Failed to search %s: %v", root, err)
+		}
+		documents = append(documents, found...)
+	}
+
+	manifest := persisted.Manifest{Format: persisted.ManifestFormat, Version: 1, Operations: []persisted.Operation{}}
+	seen := make(map[string]bool)
+	failed := false
+	for _, doc := range documents {
+		op, err := operation(schema, doc)
+		if err != nil {
+			log.Printf("%s: %v", doc.source, err)
+			failed = true
+			continue
+		}
+		if op == nil || seen[op.ID] {
+			continue
+		}
+		seen[op.ID] = true
+		manifest.Operations = append(manifest.Operations, *op)
+	}
+	if failed {
+		log.Fatal("Manifest not written, fix the documents above first")
+	}
+
+	sort.Slice(manifest.Operations, func(i, j int) bool {
+		a, b := manifest.Operations[i], manifest.Operations[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode manifest: %v", err)
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0o644); err != nil {
+		log.Fatalf("Failed to write manifest: %v", err)
+	}
+	log.Printf("Wrote %d operations to %s", len(manifest.Operations), *out)
+}
+
+// findDocuments returns the documents of the files under root.
+func findDocuments(root string) ([]document, error) {
+	var documents []document
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := filepath.Ext(path)
+		if !documentFiles[ext] && !scriptFiles[ext] {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if documentFiles[ext] {
+			documents = append(documents, document{source: path, body: string(content)})
+			return nil
+		}
+
+		for _, match := range taggedTemplate.FindAllStringSubmatchIndex(string(content), -1) {
+			line := strings.Count(string(content[:match[0]]), "\n") + 1
+			source := fmt.Sprintf("%s:%d", path, line)
+			body := string(content[match[2]:match[3]])
+			if strings.Contains(body, "${") {
+				// The text sent depends on what is interpolated at runtime
+				log.Printf("%s: skipping template with interpolations", source)
+				continue
+			}
+			documents = append(documents, document{source: source, body: body})
+		}
+		return nil
+	})
+	return documents, err
+}
+
+// operation validates a document and returns its manifest entry. Documents holding only
+// fragments return nil.
+func operation(schema *ast.Schema, doc document) (*persisted.Operation, error) {
+	query, errs := gqlparser.LoadQuery(schema, doc.body)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	switch len(query.Operations) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("%d operations in one document, split them so each can be sent on its own", len(query.Operations))
+	}
+
+	op := query.Operations[0]
+	return &persisted.Operation{
+		ID:     persisted.Hash(doc.body),
+		Name:   op.Name,
+		Type:   string(op.Operation),
+		Body:   doc.body,
+		Source: doc.source,
+	}, nil
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
@@ -11,6 +14,26 @@ import (
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gorilla/websocket"
 
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/apierror"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/audit"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/auth"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/batching"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/breaker"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cache"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cachecontrol"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/catalog"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/config"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/events"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/health"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/incremental"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/metrics"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/persisted"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/ratelimit"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/requestid"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/resolvers"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/responsecache"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/singleflight"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
 	// Import the graph package containing the merged resolver logic
 	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/graph"
 	// Keep generatedGraph for the schema
@@ -18,19 +41,137 @@ import (
 	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/loaders"
 )
 
-const defaultPort = "8080"
-
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = defaultPort
+	cfg := config.Load()
+	port := cfg.Port
+
+	// The same authenticator is shared by HTTP queries and websocket connections
+	authenticator := newAuthenticator(cfg)
+	wsAuth := auth.WebsocketAuth{Authenticator: authenticator, AllowedOrigins: cfg.AllowedOrigins}
+
+	// Load the symbol reference data. Without the file symbols have no reference data.
+	symbolCatalog, err := catalog.Load(cfg.SymbolCatalogFile)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		log.Printf("Symbol catalog %s not found, symbols will have no reference data", cfg.SymbolCatalogFile)
+	case err != nil:
+		log.Fatalf("Invalid symbol catalog: %v", err)
+	default:
+		log.Printf("Loaded %d symbols from %s", symbolCatalog.Len(), cfg.SymbolCatalogFile)
+		resolvers.SetCatalog(symbolCatalog)
+	}
+
+	// The simulated upstream only knows the symbols of the catalog, when there is one
+	simulated := upstream.NewSimulatedSource()
+	if symbolCatalog != nil {
+		simulated.Known = func(symbol string) bool { return symbolCatalog.Lookup(symbol) != nil }
+	}
+	loaders.SetDividendSource(simulated)
+	loaders.SetEarningsSource(simulated)
+	loaders.SetSplitSource(simulated)
+
+	// Inject upstream faults for chaos testing when enabled
+	var source upstream.KeyedDividendDateSource = simulated
+	var faults *upstream.FaultInjectingSource
+	if cfg.FaultInjectionEnabled {
+		var faultCfg upstream.FaultConfig
+		if cfg.FaultInjection != "" {
+			if err := json.Unmarshal([]byte(cfg.FaultInjection), &faultCfg); err != nil {
+				log.Fatalf("Invalid FAULT_INJECTION: %v", err)
+			}
+		}
+		log.Printf("Fault injection enabled: %+v", faultCfg)
+		faults = upstream.NewFaultInjectingSource(source, faultCfg)
+		source = faults
+	}
+
+	loaders.SetDividendDateSource(upstream.NewAlignedSource(source))
+
+	// Duplicate upstream calls stuck in the latency tail
+	if cfg.HedgePercentile > 0 {
+		loaders.SetHedging(upstream.HedgeConfig{
+			Percentile:   cfg.HedgePercentile,
+			MinDelay:     cfg.HedgeMinDelay,
+			MaxHedgeRate: cfg.HedgeMaxRate,
+		})
+	}
+
+	// Let the corporate action fields share one loader and one combined upstream call
+	if cfg.MultiFieldLoader {
+		loaders.SetCorporateActionsSource(simulated)
+	}
+
+	// Don't let a slow upstream hold up the whole response
+	loaders.SetFieldTimeout(cfg.FieldTimeout)
+	loaders.SetUpstreamTimeout(cfg.UpstreamTimeout)
+	loaders.SetBatchWait(cfg.BatchWait)
+
+	// Retry transient upstream failures for the failed keys of a batch
+	retryPolicy := upstream.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = cfg.RetryMaxAttempts
+	retryPolicy.InitialBackoff = cfg.RetryInitialBackoff
+	retryPolicy.MaxBackoff = cfg.RetryMaxBackoff
+	retryPolicy.Jitter = cfg.RetryJitter
+	loaders.SetRetryPolicy(retryPolicy)
+
+	// Share the upstream call budget between all requests
+	if cfg.UpstreamQuotaRPS > 0 {
+		loaders.SetUpstreamQuota(ratelimit.NewQuota(cfg.UpstreamQuotaRPS, cfg.UpstreamQuotaBurst, cfg.UpstreamQuotaMaxWait))
+	}
+
+	// Stop calling the upstream while it is failing
+	upstreamHealth := health.Check{Name: "upstream", Status: func() (bool, string) {
+		return true, "circuit breaker disabled"
+	}}
+	if cfg.BreakerFailureRate > 0 {
+		upstreamBreaker := breaker.New("upstream", breaker.Config{
+			Window:      cfg.BreakerWindow,
+			MinRequests: cfg.BreakerMinRequests,
+			FailureRate: cfg.BreakerFailureRate,
+			CoolDown:    cfg.BreakerCoolDown,
+		})
+		loaders.SetCircuitBreaker(upstreamBreaker)
+		metrics.NewGaugeFunc("upstream_circuit_breaker_state",
+			"State of the upstream circuit breaker: 0 closed, 1 half-open, 2 open.",
+			func() float64 { return float64(upstreamBreaker.State()) })
+		upstreamHealth.Status = func() (bool, string) {
+			state := upstreamBreaker.State()
+			return state == breaker.Closed, "circuit breaker " + state.String()
+		}
+	}
+
+	// Keep the audit trail of admin mutations apart from the server log when asked to
+	if cfg.AuditLogFile != "" {
+		auditLog, err := os.OpenFile(cfg.AuditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+		defer auditLog.Close()
+		audit.SetOutput(auditLog)
 	}
 
 	// Create resolver using the unified NewResolver from internal/graph/resolver.go
 	resolver := graph.NewResolver() // Use the resolver from internal/graph
 
 	// Create a handler.Server manually using the generated schema and the unified resolver
-	srv := handler.New(generatedGraph.NewExecutableSchema(generatedGraph.Config{Resolvers: resolver}))
+	srv := handler.New(generatedGraph.NewExecutableSchema(generatedGraph.Config{
+		Resolvers:  resolver,
+		Directives: generatedGraph.DirectiveRoot{Auth: auth.Directive, CacheControl: cachecontrol.Directive, SingleFlight: singleflight.Directive},
+	}))
+
+	// Accept arrays of operations in one POST. Transports are tried in order, and this one
+	// must come before POST, which doesn't accept arrays.
+	if cfg.MaxBatchSize > 0 {
+		srv.AddTransport(batching.Transport{MaxBatchSize: cfg.MaxBatchSize})
+	}
+
+	// Show errors with a code from the taxonomy and a safe message, and log panics
+	srv.SetErrorPresenter(apierror.Presenter)
+	srv.SetRecoverFunc(apierror.Recover)
+
+	// Deliver @defer fragments and @stream items after the initial payload to POSTs accepting
+	// multipart/mixed. It must come before POST, which accepts any JSON POST.
+	srv.AddTransport(incremental.MultipartMixed{})
 
 	// Add transports (order might matter depending on routing library)
 	srv.AddTransport(transport.Options{})       // Needs POST, GET, etc. - Options{} provides defaults
@@ -41,23 +182,113 @@ func main() {
 	// Add WebSocket support for subscriptions
 	srv.AddTransport(transport.Websocket{
 		Upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				// Allow all origins for simplicity in demo
-				return true
-			},
+			CheckOrigin: wsAuth.CheckOrigin,
 		},
+		InitFunc:  wsAuth.InitFunc,
+		ErrorFunc: wsAuth.ErrorFunc,
+		CloseFunc: wsAuth.CloseFunc,
 	})
 
 	// Enable introspection for better developer experience
 	srv.Use(extension.Introspection{})
 
+	// Let clients send query hashes instead of query texts. In production, a manifest can
+	// restrict operations to the ones extracted from the client code.
+	if cfg.PersistedQueryManifest != "" {
+		manifest, err := persisted.LoadManifest(cfg.PersistedQueryManifest)
+		if err != nil {
+			log.Fatalf("Invalid persisted query manifest: %v", err)
+		}
+		log.Printf("Only executing the %d operations of %s", len(manifest.Operations), cfg.PersistedQueryManifest)
+		srv.Use(persisted.NewAllowList(manifest))
+	} else {
+		var queries persisted.QueryStore
+		switch cfg.APQStore {
+		case "lru":
+			queries = persisted.NewLRUStore(cfg.APQCacheSize)
+		case "cache":
+			queries = persisted.NewCacheStore(cache.NewNamespace[string]("apq").WithTTL(cfg.APQCacheTTL))
+		default:
+			log.Fatalf("Invalid APQ_STORE %q, want lru or cache", cfg.APQStore)
+		}
+		srv.Use(extension.AutomaticPersistedQuery{Cache: queries})
+	}
+
+	// Give every subscription event a fresh loader
+	srv.AroundResponses(loaders.AroundResponses)
+
+	// Track the fields marked with @singleFlight per operation, event and connection
+	singleflight.SetIdentity(resolvers.ParentIdentity)
+	srv.AroundOperations(singleflight.AroundOperations)
+	srv.AroundResponses(singleflight.AroundResponses)
+
+	// Collect the @cacheControl hints of GET queries
+	srv.AroundRootFields(cachecontrol.AroundRootFields)
+	srv.AroundResponses(cachecontrol.AroundResponses)
+
+	// Limit each client by principal (or IP when unauthenticated)
+	var queryHandler http.Handler = cachecontrol.Middleware(loaders.Middleware(singleflight.Middleware(srv)))
+	if cfg.RateLimitRPS > 0 {
+		limiter := ratelimit.NewKeyedLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+		srv.AroundOperations(ratelimit.AroundOperations(limiter))
+		queryHandler = ratelimit.Middleware(limiter, queryHandler)
+	}
+
+	// Skip execution of repeated queries, dropping responses whose dividend dates change
+	if cfg.ResponseCacheEnabled {
+		responses := responsecache.New(responsecache.NewMemoryStore())
+		events.Listen(responses.Invalidate)
+		srv.AroundOperations(responses.AroundOperations)
+		log.Println("Response cache enabled")
+	}
+
+	// Execute @stream lists in parts. Registered after the response cache and rate limiting,
+	// which see the operation as a whole.
+	srv.AroundOperations(incremental.AroundOperations)
+
 	// Create the handler chain with the dataloader middleware
+	if faults != nil {
+		http.Handle("/admin/faults", auth.Middleware(authenticator, auth.RequireRole("admin", faults.AdminHandler())))
+	}
+	http.Handle("/health", health.Handler(upstreamHealth))
+	http.Handle("/metrics", metrics.Handler())
 	http.Handle("/", playground.Handler("GraphQL Resolver Batch Cache Demo", "/query"))
-	http.Handle("/query", loaders.Middleware(srv))
+	http.Handle("/query", requestid.Middleware(auth.Middleware(authenticator, queryHandler)))
 
 	// Start the server
 	log.Printf("Server running at http://localhost:%s/", port)
 	log.Printf("GraphQL endpoint: http://localhost:%s/query", port)
 	log.Printf("GraphQL playground: http://localhost:%s/", port)
+	log.Printf("Health: http://localhost:%s/health, metrics: http://localhost:%s/metrics", port, port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
+
+// newAuthenticator builds the authenticator chain from the configuration.
+// It returns nil when no authentication method is configured.
+func newAuthenticator(cfg config.Config) auth.Authenticator {
+	if !cfg.AuthEnabled() {
+		log.Println("No API_KEYS or JWT keys configured, authentication disabled")
+		return nil
+	}
+
+	var chain auth.Chain
+	if len(cfg.APIKeys) > 0 {
+		chain = append(chain, auth.NewStaticKeyAuthenticator(cfg.APIKeys))
+	}
+	if cfg.JWTEnabled() {
+		jwtCfg := auth.JWTConfig{
+			HMACSecret: []byte(cfg.JWTSecret),
+			Issuer:     cfg.JWTIssuer,
+			Audience:   cfg.JWTAudience,
+		}
+		if cfg.JWTPublicKeyFile != "" {
+			key, err := auth.LoadRSAPublicKey(cfg.JWTPublicKeyFile)
+			if err != nil {
+				log.Fatalf("Failed to load JWT public key: %v", err)
+			}
+			jwtCfg.RSAPublicKey = key
+		}
+		chain = append(chain, auth.NewJWTAuthenticator(jwtCfg))
+	}
+	return chain
+}
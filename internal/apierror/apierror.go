@@ -0,0 +1,50 @@
+// Package apierror defines the codes clients find in extensions.code of GraphQL errors, and
+// the gqlgen error presenter and recover func mapping Go errors to them with safe messages.
+package apierror
+
+// Code classifies an error for clients, in extensions.code.
+type Code string
+
+// The error codes of the taxonomy. Errors built by the resolvers as GraphQL errors keep
+// their own codes, such as BAD_USER_INPUT, FORBIDDEN or BATCH_TOO_LARGE.
+const (
+	// UpstreamUnavailable is for upstream calls that failed, or were rejected by the open
+	// circuit breaker. Retrying later may succeed.
+	UpstreamUnavailable Code = "UPSTREAM_UNAVAILABLE"
+	// NotFound is for symbols the upstream doesn't know.
+	NotFound Code = "NOT_FOUND"
+	// InvalidSymbol is for malformed symbol names in arguments.
+	InvalidSymbol Code = "INVALID_SYMBOL"
+	// RateLimited is for requests over the client rate limit or the upstream quota.
+	RateLimited Code = "RATE_LIMITED"
+	// UpstreamTimeout is for fields whose upstream data didn't arrive in time.
+	UpstreamTimeout Code = "UPSTREAM_TIMEOUT"
+	// Cancelled is for fields abandoned because the client cancelled the request.
+	Cancelled Code = "CANCELLED"
+	// Internal is for every other error, including panics. Their message is never shown.
+	Internal Code = "INTERNAL"
+)
+
+// internalMessage replaces the message of INTERNAL errors.
+const internalMessage = "internal server error"
+
+// Error is an error with a code of the taxonomy and a message safe to show to clients.
+type Error struct {
+	Code Code
+	// Message is shown to clients.
+	Message string
+	// Extensions are added to the extensions of the GraphQL error, next to the code.
+	Extensions map[string]any
+	// Err is the underlying error. It is logged for INTERNAL errors, but never shown.
+	Err error
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,130 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/breaker"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/loaders"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/ratelimit"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/requestid"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/symbols"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
+)
+
+// Classify maps an error to the taxonomy. Errors of the server's own packages get their code
+// and a message describing them; any other error is INTERNAL.
+func Classify(err error) *Error {
+	var (
+		apiErr     *Error
+		limitErr   *ratelimit.Error
+		timeoutErr *loaders.FieldTimeoutError
+		openErr    *breaker.OpenError
+		symbolErr  *symbols.Error
+	)
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case errors.As(err, &limitErr):
+		return &Error{
+			Code:       RateLimited,
+			Message:    limitErr.Error(),
+			Extensions: map[string]any{"scope": limitErr.Scope, "retryAfter": limitErr.RetryAfterSeconds()},
+			Err:        err,
+		}
+	case errors.Is(err, ratelimit.ErrLimited):
+		return &Error{Code: RateLimited, Message: "rate limit exceeded", Err: err}
+	case errors.As(err, &timeoutErr):
+		return &Error{
+			Code:       UpstreamTimeout,
+			Message:    timeoutErr.Error(),
+			Extensions: map[string]any{"timeoutMs": timeoutErr.Timeout.Milliseconds()},
+			Err:        err,
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Code: UpstreamTimeout, Message: "upstream did not respond in time", Err: err}
+	case errors.Is(err, context.Canceled):
+		return &Error{Code: Cancelled, Message: "request was cancelled", Err: err}
+	case errors.As(err, &openErr):
+		return &Error{
+			Code:       UpstreamUnavailable,
+			Message:    "upstream is unavailable, retry later",
+			Extensions: map[string]any{"retryAfter": int(openErr.RetryAfter.Seconds()) + 1},
+			Err:        err,
+		}
+	case upstream.IsRetryable(err):
+		// Still failing after the retries
+		return &Error{Code: UpstreamUnavailable, Message: "upstream is unavailable, retry later", Err: err}
+	case errors.Is(err, upstream.ErrNotFound):
+		return &Error{Code: NotFound, Message: err.Error(), Err: err}
+	case errors.As(err, &symbolErr):
+		return &Error{Code: InvalidSymbol, Message: symbolErr.Error(), Err: err}
+	default:
+		return &Error{Code: Internal, Message: internalMessage, Err: err}
+	}
+}
+
+// Presenter is the gqlgen error presenter. GraphQL errors built by the resolvers or gqlgen,
+// with a code, without an underlying error, or on an argument, are meant for clients and shown
+// as they are. Every other error, such as an error returned by a loader, is classified, shown
+// with its code and safe message, and logged with the request ID when INTERNAL.
+func Presenter(ctx context.Context, err error) *gqlerror.Error {
+	path := graphql.GetPath(ctx)
+	// gqlgen wraps the errors returned by resolvers into GraphQL errors on their path
+	var gqlErr *gqlerror.Error
+	if !errors.As(err, &gqlErr) {
+		gqlErr = gqlerror.WrapPath(path, err)
+	}
+	if gqlErr.Err == nil || gqlErr.Extensions["code"] != nil || !samePath(gqlErr.Path, path) {
+		return gqlErr
+	}
+
+	classified := Classify(gqlErr.Err)
+	extensions := map[string]any{"code": classified.Code}
+	for name, value := range classified.Extensions {
+		extensions[name] = value
+	}
+	if classified.Code == Internal {
+		id := requestid.FromContext(ctx)
+		extensions["requestId"] = id
+		cause := classified.Err
+		if cause == nil {
+			cause = gqlErr.Err
+		}
+		log.Printf("Internal error at %s (request %s): %v", path, id, cause)
+	}
+	return &gqlerror.Error{
+		Err:        gqlErr.Err,
+		Message:    classified.Message,
+		Path:       gqlErr.Path,
+		Extensions: extensions,
+	}
+}
+
+// samePath reports whether two paths are equal. Errors gqlgen raises while reading arguments
+// are on the argument's path, below the field's.
+func samePath(a, b ast.Path) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Recover is the gqlgen recover func. It logs a panic with its stack and the request ID, and
+// fails the field with an INTERNAL error instead of the panic value.
+func Recover(ctx context.Context, v any) error {
+	log.Printf("Panic at %s (request %s): %v\n%s", graphql.GetPath(ctx), requestid.FromContext(ctx), v, debug.Stack())
+	return &Error{Code: Internal, Message: internalMessage, Err: fmt.Errorf("panic: %v", v)}
+}
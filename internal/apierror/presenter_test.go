@@ -0,0 +1,234 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/breaker"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/loaders"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/ratelimit"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/requestid"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/symbols"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
+)
+
+// fieldContext returns the context of a request with the given ID, resolving the field alias.
+func fieldContext(t *testing.T, id, alias string) context.Context {
+	t.Helper()
+	var ctx context.Context
+	handler := requestid.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/query", nil)
+	r.Header.Set(requestid.Header, id)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	return graphql.WithFieldContext(ctx, &graphql.FieldContext{
+		Field: graphql.CollectedField{Field: &ast.Field{Alias: alias, Name: alias}},
+	})
+}
+
+func TestPresenter(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		code       Code
+		message    string
+		extensions map[string]any
+	}{
+		{
+			name:    "upstream failure",
+			err:     fmt.Errorf("connection reset: %w", upstream.ErrTransient),
+			code:    UpstreamUnavailable,
+			message: "upstream is unavailable, retry later",
+		},
+		{
+			name:       "open circuit breaker",
+			err:        &breaker.OpenError{Name: "upstream", RetryAfter: 4500 * time.Millisecond},
+			code:       UpstreamUnavailable,
+			message:    "upstream is unavailable, retry later",
+			extensions: map[string]any{"retryAfter": 5},
+		},
+		{
+			name:    "unknown symbol",
+			err:     upstream.ErrNotFound,
+			code:    NotFound,
+			message: "symbol not found",
+		},
+		{
+			name:    "malformed symbol",
+			err:     &symbols.Error{Input: "A$PL", Reason: "invalid character"},
+			code:    InvalidSymbol,
+			message: (&symbols.Error{Input: "A$PL", Reason: "invalid character"}).Error(),
+		},
+		{
+			name:       "upstream quota",
+			err:        &ratelimit.Error{Scope: ratelimit.ScopeUpstream, RetryAfter: 1500 * time.Millisecond},
+			code:       RateLimited,
+			message:    "upstream rate limit exceeded, retry after 2s",
+			extensions: map[string]any{"scope": ratelimit.ScopeUpstream, "retryAfter": 2},
+		},
+		{
+			name:    "rate limited without details",
+			err:     fmt.Errorf("quota: %w", ratelimit.ErrLimited),
+			code:    RateLimited,
+			message: "rate limit exceeded",
+		},
+		{
+			name:       "field timeout",
+			err:        &loaders.FieldTimeoutError{Key: "AAPL", Timeout: 2 * time.Second},
+			code:       UpstreamTimeout,
+			message:    "upstream did not respond for AAPL within 2s",
+			extensions: map[string]any{"timeoutMs": int64(2000)},
+		},
+		{
+			name:    "deadline",
+			err:     fmt.Errorf("fetching: %w", context.DeadlineExceeded),
+			code:    UpstreamTimeout,
+			message: "upstream did not respond in time",
+		},
+		{
+			name:    "cancelled",
+			err:     fmt.Errorf("fetching: %w", context.Canceled),
+			code:    Cancelled,
+			message: "request was cancelled",
+		},
+		{
+			name:       "coded error",
+			err:        &Error{Code: NotFound, Message: "no such listing", Extensions: map[string]any{"symbol": "XYZ"}},
+			code:       NotFound,
+			message:    "no such listing",
+			extensions: map[string]any{"symbol": "XYZ"},
+		},
+		{
+			name:       "anything else",
+			err:        errors.New("pq: password authentication failed"),
+			code:       Internal,
+			message:    "internal server error",
+			extensions: map[string]any{"requestId": "req-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := fieldContext(t, "req-1", "symbols")
+			// Resolver errors reach the presenter both as they are and wrapped on their path
+			for _, err := range []error{tt.err, gqlerror.WrapPath(ast.Path{ast.PathName("symbols")}, tt.err)} {
+				got := Presenter(ctx, err)
+
+				if got.Message != tt.message {
+					t.Errorf("message = %q, want %q", got.Message, tt.message)
+				}
+				want := map[string]any{"code": tt.code}
+				for name, value := range tt.extensions {
+					want[name] = value
+				}
+				if !reflect.DeepEqual(got.Extensions, want) {
+					t.Errorf("extensions = %v, want %v", got.Extensions, want)
+				}
+				if got.Path.String() != "symbols" {
+					t.Errorf("path = %s, want symbols", got.Path)
+				}
+				if !errors.Is(got, tt.err) {
+					t.Errorf("presented error doesn't wrap %v", tt.err)
+				}
+			}
+		})
+	}
+}
+
+func TestPresenterKeepsClientErrors(t *testing.T) {
+	ctx := fieldContext(t, "req-1", "symbols")
+	tests := []struct {
+		name string
+		err  *gqlerror.Error
+	}{
+		{
+			name: "with a code",
+			err: &gqlerror.Error{
+				Message:    "missing required role admin",
+				Path:       ast.Path{ast.PathName("symbols")},
+				Extensions: map[string]any{"code": "FORBIDDEN"},
+				Err:        errors.New("forbidden"),
+			},
+		},
+		{
+			name: "without an underlying error",
+			err:  &gqlerror.Error{Message: "Cannot query field", Path: ast.Path{ast.PathName("symbols")}},
+		},
+		{
+			name: "on an argument",
+			err: &gqlerror.Error{
+				Message: "cannot be null",
+				Path:    ast.Path{ast.PathName("symbols"), ast.PathName("names")},
+				Err:     errors.New("cannot be null"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Presenter(ctx, tt.err); got != tt.err {
+				t.Errorf("Presenter() = %v, want the error unchanged", got)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name  string
+		id    string
+		value any
+	}{
+		{name: "panic with a string", id: "req-1", value: "boom"},
+		{name: "panic with an error", id: "req-2", value: errors.New("secret dsn")},
+		{name: "nil map write", id: "client-chosen.id_3", value: fmt.Errorf("assignment to entry in nil map")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := fieldContext(t, tt.id, "symbol")
+
+			err := Recover(ctx, tt.value)
+			var apiErr *Error
+			if !errors.As(err, &apiErr) || apiErr.Code != Internal {
+				t.Fatalf("Recover() = %v, want an INTERNAL error", err)
+			}
+
+			got := Presenter(ctx, err)
+			if got.Message != "internal server error" {
+				t.Errorf("message = %q, the panic value must not be shown", got.Message)
+			}
+			if got.Extensions["code"] != Internal {
+				t.Errorf("code = %v, want %s", got.Extensions["code"], Internal)
+			}
+			if got.Extensions["requestId"] != tt.id {
+				t.Errorf("requestId = %v, want %s", got.Extensions["requestId"], tt.id)
+			}
+		})
+	}
+}
+
+func TestRequestIDGeneratedForInvalidHeader(t *testing.T) {
+	ctx := fieldContext(t, "not a valid id!", "symbol")
+	got := Presenter(ctx, errors.New("boom"))
+
+	id, _ := got.Extensions["requestId"].(string)
+	if id == "" || id == "not a valid id!" {
+		t.Errorf("requestId = %q, want a generated ID", id)
+	}
+	if id != requestid.FromContext(ctx) {
+		t.Errorf("requestId = %q, want the request's ID %q", id, requestid.FromContext(ctx))
+	}
+}
@@ -0,0 +1,64 @@
+// Package audit records administrative actions, such as cache invalidations and overrides,
+// with the principal who performed them.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/auth"
+)
+
+// Event is one audited action.
+type Event struct {
+	Time time.Time `json:"time"`
+	// Actor is the subject of the principal who performed the action.
+	Actor  string      `json:"actor"`
+	Method auth.Method `json:"method,omitempty"`
+	// Action names what was done, e.g. "overrideDividendDate".
+	Action  string         `json:"action"`
+	Symbols []string       `json:"symbols,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+var (
+	mu sync.Mutex
+	// output receives one JSON event per line. Nil writes events to the standard logger.
+	output io.Writer
+)
+
+// SetOutput sends audit events to w, one JSON object per line, instead of the server log.
+// It should be called once at startup, before the server accepts requests.
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	output = w
+}
+
+// Record audits an action performed by the principal of ctx.
+func Record(ctx context.Context, action string, symbols []string, details map[string]any) {
+	event := Event{Time: time.Now().UTC(), Actor: "unknown", Action: action, Symbols: symbols, Details: details}
+	if principal := auth.PrincipalFrom(ctx); principal != nil {
+		event.Actor, event.Method = principal.Subject, principal.Method
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode audit event for %s: %v", action, err)
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if output == nil {
+		log.Printf("AUDIT %s", line)
+		return
+	}
+	if _, err := output.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to write audit event %s: %v", line, err)
+	}
+}
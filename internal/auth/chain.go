@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// Chain tries each authenticator in turn and returns the first principal accepted.
+// It lets static API keys and JWT bearer tokens be used side by side.
+type Chain []Authenticator
+
+// Authenticate implements Authenticator.
+func (c Chain) Authenticate(ctx context.Context, creds Credentials) (*Principal, error) {
+	if creds.Empty() {
+		return nil, ErrMissingCredentials
+	}
+
+	// Report the most specific failure: an invalid credential beats a missing one.
+	var lastErr error = ErrMissingCredentials
+	for _, authenticator := range c {
+		principal, err := authenticator.Authenticate(ctx, creds)
+		if err == nil {
+			return principal, nil
+		}
+		if !errors.Is(err, ErrMissingCredentials) {
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
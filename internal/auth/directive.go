@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cachecontrol"
+)
+
+// Directive implements the @auth(requires: [...]) schema directive. The field only resolves
+// for an authenticated principal holding every listed role. When authentication is disabled
+// the Anonymous principal only holds its own roles, so admin fields are refused. Responses
+// resolved for an authenticated principal are made private.
+func Directive(ctx context.Context, obj any, next graphql.Resolver, requires []string) (any, error) {
+	principal := PrincipalFrom(ctx)
+	if principal == nil {
+		return nil, authError(ctx, "authentication required", "UNAUTHENTICATED")
+	}
+
+	for _, role := range requires {
+		if principal.HasRole(role) {
+			continue
+		}
+		if principal.Method == MethodAnonymous {
+			return nil, authError(ctx, "authentication is disabled, role "+role+" can't be granted", "FORBIDDEN")
+		}
+		return nil, authError(ctx, "missing required role "+role, "FORBIDDEN")
+	}
+
+	// What an authenticated principal may see is for its own cache only
+	if principal.Method != MethodAnonymous {
+		cachecontrol.MakePrivate(ctx)
+	}
+	return next(ctx)
+}
+
+// authError builds a GraphQL error for the current field with an extensions code.
+func authError(ctx context.Context, message, code string) error {
+	return &gqlerror.Error{
+		Path:       graphql.GetPath(ctx),
+		Message:    message,
+		Extensions: map[string]any{"code": code},
+	}
+}
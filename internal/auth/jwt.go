@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// MethodJWT is used for principals authenticated with a JWT bearer token.
+const MethodJWT Method = "jwt"
+
+// clockSkew is the leeway applied when checking exp and nbf claims.
+const clockSkew = 30 * time.Second
+
+// JWTConfig holds the locally supplied keys and expected claims for JWT verification.
+// At least one of HMACSecret or RSAPublicKey must be set.
+type JWTConfig struct {
+	// HMACSecret verifies HS256 tokens.
+	HMACSecret []byte
+	// RSAPublicKey verifies RS256 tokens.
+	RSAPublicKey *rsa.PublicKey
+	// Issuer, if set, must match the iss claim.
+	Issuer string
+	// Audience, if set, must be present in the aud claim.
+	Audience string
+}
+
+// JWTAuthenticator authenticates bearer tokens signed with HS256 or RS256.
+type JWTAuthenticator struct {
+	cfg JWTConfig
+	now func() time.Time
+}
+
+// NewJWTAuthenticator creates a JWT authenticator from the given configuration.
+func NewJWTAuthenticator(cfg JWTConfig) *JWTAuthenticator {
+	return &JWTAuthenticator{cfg: cfg, now: time.Now}
+}
+
+// jwtHeader is the decoded JOSE header of a token.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+// jwtClaims are the registered and custom claims we understand.
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	Roles     []string `json:"roles"`
+	Scope     string   `json:"scope"`
+}
+
+// audience accepts both the single string and the array form of the aud claim.
+type audience []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// Authenticate implements Authenticator.
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Principal, error) {
+	if creds.BearerToken == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	claims, err := a.verify(creds.BearerToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	roles := append([]string(nil), claims.Roles...)
+	roles = append(roles, strings.Fields(claims.Scope)...)
+	return &Principal{Subject: claims.Subject, Method: MethodJWT, Roles: roles}, nil
+}
+
+// verify checks the token signature and registered claims and returns the claims.
+func (a *JWTAuthenticator) verify(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("invalid signature encoding")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	// Only accept the algorithm matching a configured key, so an RS256 public key
+	// can never be used as an HS256 secret.
+	switch header.Alg {
+	case "HS256":
+		if len(a.cfg.HMACSecret) == 0 {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, a.cfg.HMACSecret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, errors.New("signature mismatch")
+		}
+	case "RS256":
+		if a.cfg.RSAPublicKey == nil {
+			return nil, errors.New("RS256 tokens are not accepted")
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(a.cfg.RSAPublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, errors.New("signature mismatch")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+
+	now := a.now()
+	if claims.ExpiresAt != nil && now.After(unixTime(*claims.ExpiresAt).Add(clockSkew)) {
+		return nil, errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(unixTime(*claims.NotBefore)) {
+		return nil, errors.New("token not yet valid")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("missing sub claim")
+	}
+	if a.cfg.Issuer != "" && claims.Issuer != a.cfg.Issuer {
+		return nil, errors.New("unexpected issuer")
+	}
+	if a.cfg.Audience != "" && !contains(claims.Audience, a.cfg.Audience) {
+		return nil, errors.New("unexpected audience")
+	}
+
+	return &claims, nil
+}
+
+// decodeSegment base64url-decodes a token segment and unmarshals it as JSON.
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// unixTime converts a NumericDate claim into a time.Time.
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// contains reports whether values contains s.
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRSAPublicKey reads a PEM encoded RSA public key or certificate from disk.
+func LoadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s does not contain an RSA public key", path)
+		}
+		return rsaKey, nil
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%s does not contain an RSA certificate", path)
+		}
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+}
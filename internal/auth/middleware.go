@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gorilla/websocket"
+)
+
+// Middleware authenticates HTTP requests and adds the principal to the request context.
+// Requests without credentials continue unauthenticated and are rejected by fields guarded
+// with @auth; requests with invalid credentials are rejected with 401. A nil authenticator
+// disables authentication and every request runs as the Anonymous principal.
+// Websocket upgrades are authenticated later from the connection_init payload.
+func Middleware(authenticator Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authenticator == nil {
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), Anonymous)))
+			return
+		}
+
+		creds := CredentialsFromRequest(r)
+		if creds.Empty() || websocket.IsWebSocketUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := authenticator.Authenticate(r.Context(), creds)
+		if err != nil {
+			log.Printf("HTTP request rejected: %v", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="graphql"`)
+			transport.SendErrorf(w, http.StatusUnauthorized, "%s", err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// CredentialsFromRequest extracts credentials from the X-API-Key and Authorization headers.
+func CredentialsFromRequest(r *http.Request) Credentials {
+	return Credentials{
+		APIKey:      r.Header.Get("X-API-Key"),
+		BearerToken: bearerToken(r.Header.Get("Authorization")),
+	}
+}
+
+// RequireRole only lets requests through whose principal holds role. It must run after
+// Middleware. The Anonymous principal holds no role, so when authentication is disabled
+// every request is refused.
+func RequireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := PrincipalFrom(r.Context())
+		switch {
+		case principal == nil:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="graphql"`)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+		case principal.Method == MethodAnonymous:
+			http.Error(w, "authentication is disabled, role "+role+" can't be granted", http.StatusForbidden)
+		case !principal.HasRole(role):
+			http.Error(w, "missing required role "+role, http.StatusForbidden)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
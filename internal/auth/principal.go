@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// Method identifies how a principal was authenticated.
+type Method string
+
+const (
+	// MethodAnonymous is used when authentication is disabled.
+	MethodAnonymous Method = "anonymous"
+	// MethodAPIKey is used for principals authenticated with a static API key.
+	MethodAPIKey Method = "api_key"
+)
+
+var (
+	// ErrMissingCredentials is returned when a request carries no credentials.
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials is returned when the supplied credentials are not accepted.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrForbidden is returned when the principal is not allowed to perform an operation.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Principal is the authenticated identity attached to a request or websocket connection.
+// HTTP queries and subscriptions share this model so resolvers don't care about the transport.
+type Principal struct {
+	// Subject identifies the caller, e.g. the owner of an API key.
+	Subject string
+	// Method records how the principal was authenticated.
+	Method Method
+	// Roles holds the roles granted to the principal.
+	Roles []string
+}
+
+// Anonymous is the principal used when authentication is disabled. It may read, but never
+// holds the admin role: administrative operations need authentication to be enabled.
+var Anonymous = &Principal{Subject: "anonymous", Method: MethodAnonymous, Roles: []string{"reader"}}
+
+// HasRole reports whether the principal has been granted the given role.
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Credentials are the raw credentials presented by a client, either through HTTP headers
+// or through the websocket connection_init payload.
+type Credentials struct {
+	APIKey      string
+	BearerToken string
+}
+
+// Empty reports whether no credentials were presented.
+func (c Credentials) Empty() bool {
+	return c.APIKey == "" && c.BearerToken == ""
+}
+
+// Authenticator verifies credentials and resolves them to a principal.
+type Authenticator interface {
+	Authenticate(ctx context.Context, creds Credentials) (*Principal, error)
+}
+
+// Context key for the principal
+type contextKey string
+
+// PrincipalKey is the key for the principal in the context
+const PrincipalKey = contextKey("principal")
+
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, PrincipalKey, p)
+}
+
+// PrincipalFrom returns the principal from the context, or nil if there is none.
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(PrincipalKey).(*Principal)
+	return p
+}
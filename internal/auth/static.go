@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+)
+
+// StaticKeyAuthenticator authenticates clients against a fixed set of API keys.
+// A key may be presented either as an API key or as a bearer token.
+type StaticKeyAuthenticator struct {
+	// keys maps each API key to the principal it authenticates as.
+	keys map[string]*Principal
+}
+
+// NewStaticKeyAuthenticator creates an authenticator from a map of API keys to owners.
+// Each owner is written as "subject" or "subject:role1|role2".
+func NewStaticKeyAuthenticator(keys map[string]string) *StaticKeyAuthenticator {
+	principals := make(map[string]*Principal, len(keys))
+	for key, owner := range keys {
+		subject, roles, _ := strings.Cut(owner, ":")
+		principal := &Principal{Subject: subject, Method: MethodAPIKey}
+		if roles != "" {
+			principal.Roles = strings.Split(roles, "|")
+		}
+		principals[key] = principal
+	}
+	return &StaticKeyAuthenticator{keys: principals}
+}
+
+// Authenticate implements Authenticator.
+func (a *StaticKeyAuthenticator) Authenticate(ctx context.Context, creds Credentials) (*Principal, error) {
+	if creds.Empty() {
+		return nil, ErrMissingCredentials
+	}
+
+	presented := creds.APIKey
+	if presented == "" {
+		presented = creds.BearerToken
+	}
+
+	// Compare against every key in constant time so the lookup doesn't leak key prefixes.
+	var principal *Principal
+	for key, owner := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(presented)) == 1 {
+			principal = owner
+		}
+	}
+	if principal == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return principal, nil
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+// Codes reported in the text of the connection_error message when a connection is rejected.
+// They are not close codes: gqlgen always closes a rejected connection normally (1000), so
+// the numbers of the graphql-transport-ws close codes are only carried in the message.
+const (
+	CodeUnauthorized = 4401
+	CodeForbidden    = 4403
+)
+
+// RejectedError is returned by InitFunc for rejected connections. gqlgen sends its message
+// to the client in a connection_error message, then closes the connection with code 1000.
+type RejectedError struct {
+	// Code is CodeUnauthorized or CodeForbidden.
+	Code int
+	Err  error
+}
+
+// Error implements error.
+func (e *RejectedError) Error() string {
+	reason := "Unauthorized"
+	if e.Code == CodeForbidden {
+		reason = "Forbidden"
+	}
+	return fmt.Sprintf("%d %s: %v", e.Code, reason, e.Err)
+}
+
+// Unwrap returns the authentication error.
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// WebsocketAuth authenticates websocket connections from the connection_init payload
+// and restricts which origins may open them.
+type WebsocketAuth struct {
+	// Authenticator verifies the connection_init credentials. Nil disables authentication
+	// and every connection runs as the Anonymous principal.
+	Authenticator Authenticator
+	// AllowedOrigins lists the accepted Origin header values. An empty list only allows
+	// same-origin connections; "*" allows every origin.
+	AllowedOrigins []string
+}
+
+// CheckOrigin is used as the websocket.Upgrader CheckOrigin function.
+func (a WebsocketAuth) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// Non-browser clients don't send an Origin header.
+		return true
+	}
+
+	if len(a.AllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		if err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+	}
+	for _, allowed := range a.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	log.Printf("Rejecting websocket connection from origin %s", origin)
+	return false
+}
+
+// InitFunc is used as the transport.Websocket InitFunc. It authenticates the credentials
+// in the connection_init payload and stores the principal in the connection context,
+// so every subscription event resolved on the connection sees the same identity.
+func (a WebsocketAuth) InitFunc(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+	if a.Authenticator == nil {
+		return WithPrincipal(ctx, Anonymous), nil, nil
+	}
+
+	principal, err := a.Authenticator.Authenticate(ctx, CredentialsFromInitPayload(payload))
+	if err != nil {
+		log.Printf("Websocket connection rejected: %v", err)
+		code := CodeUnauthorized
+		if errors.Is(err, ErrForbidden) {
+			code = CodeForbidden
+		}
+		return ctx, nil, &RejectedError{Code: code, Err: err}
+	}
+
+	log.Printf("Websocket connection authenticated as %s (%s)", principal.Subject, principal.Method)
+	return WithPrincipal(ctx, principal), nil, nil
+}
+
+// ErrorFunc is used as the transport.Websocket ErrorFunc. It logs read and write failures.
+func (a WebsocketAuth) ErrorFunc(ctx context.Context, err error) {
+	log.Printf("Websocket connection error: %v", err)
+}
+
+// CloseFunc is used as the transport.Websocket CloseFunc. It logs closed connections with
+// their close code and principal.
+func (a WebsocketAuth) CloseFunc(ctx context.Context, closeCode int) {
+	if principal := PrincipalFrom(ctx); principal != nil {
+		log.Printf("Websocket connection of %s closed with code %d", principal.Subject, closeCode)
+		return
+	}
+	log.Printf("Websocket connection closed with code %d", closeCode)
+}
+
+// CredentialsFromInitPayload extracts credentials from a connection_init payload.
+// Clients may send either an apiKey entry or an Authorization bearer token.
+func CredentialsFromInitPayload(payload transport.InitPayload) Credentials {
+	apiKey := payload.GetString("apiKey")
+	if apiKey == "" {
+		apiKey = payload.GetString("X-API-Key")
+	}
+	return Credentials{
+		APIKey:      apiKey,
+		BearerToken: bearerToken(payload.Authorization()),
+	}
+}
+
+// bearerToken returns the token from an "Authorization: Bearer <token>" value.
+func bearerToken(authorization string) string {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,197 @@
+// Package batching lets clients send several GraphQL operations in one HTTP request, as a
+// JSON array, and get an array of results back in the same order.
+package batching
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// DefaultMaxBodySize is the largest request body accepted when MaxBodySize isn't set.
+const DefaultMaxBodySize = 1 << 20
+
+// Transport is a gqlgen transport for POST requests whose body is an array of operations.
+// The operations run concurrently in the context of the request, so they share its
+// request-scoped state, such as dataloaders. Each result carries its own errors.
+// It must be added before transport.POST, which would reject the array.
+type Transport struct {
+	// MaxBatchSize is the largest number of operations accepted in one request.
+	MaxBatchSize int
+	// MaxBodySize is the largest request body accepted, in bytes. 0 uses DefaultMaxBodySize.
+	MaxBodySize int64
+}
+
+var _ graphql.Transport = Transport{}
+
+type contextKey struct{}
+
+// OperationIndex returns the index of the operation in its batch, or false for operations
+// that weren't sent in a batch.
+func OperationIndex(ctx context.Context) (int, bool) {
+	index, ok := ctx.Value(contextKey{}).(int)
+	return index, ok
+}
+
+// Supports implements graphql.Transport. It peeks at the start of the body, at most
+// MaxBodySize bytes, and leaves the whole body for the next transport.
+func (t Transport) Supports(r *http.Request) bool {
+	if r.Method != http.MethodPost || r.Header.Get("Upgrade") != "" || r.Body == nil {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return false
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(r.Body, t.maxBodySize()))
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(peeked), r.Body), Closer: r.Body}
+	if err != nil {
+		return false
+	}
+	trimmed := bytes.TrimSpace(peeked)
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// maxBodySize returns the configured body limit.
+func (t Transport) maxBodySize() int64 {
+	if t.MaxBodySize > 0 {
+		return t.MaxBodySize
+	}
+	return DefaultMaxBodySize
+}
+
+// readCloser puts a peeked body back together with the original body's Close.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Do implements graphql.Transport.
+func (t Transport) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	ctx := r.Context()
+	w.Header().Set("Content-Type", "application/json")
+
+	var batch []*graphql.RawParams
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, t.maxBodySize()))
+	dec.UseNumber()
+	if err := dec.Decode(&batch); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err := gqlerror.Errorf("request body exceeds the maximum of %d bytes", tooLarge.Limit)
+			err.Extensions = map[string]any{"code": "REQUEST_TOO_LARGE", "maxBodySize": tooLarge.Limit}
+			writeError(w, exec.DispatchError(ctx, gqlerror.List{err}), http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeError(w, exec.DispatchError(ctx, gqlerror.List{gqlerror.Errorf("json request body could not be decoded: %v", err)}), http.StatusBadRequest)
+		return
+	}
+	if len(batch) == 0 {
+		writeError(w, exec.DispatchError(ctx, gqlerror.List{gqlerror.Errorf("batch is empty")}), http.StatusBadRequest)
+		return
+	}
+	if len(batch) > t.MaxBatchSize {
+		err := gqlerror.Errorf("batch of %d operations exceeds the maximum of %d", len(batch), t.MaxBatchSize)
+		err.Extensions = map[string]any{"code": "BATCH_TOO_LARGE", "maxBatchSize": t.MaxBatchSize}
+		writeError(w, exec.DispatchError(ctx, gqlerror.List{err}), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	start := graphql.Now()
+	responses := make([]*graphql.Response, len(batch))
+	var wg sync.WaitGroup
+	for i, params := range batch {
+		wg.Add(1)
+		go func(i int, params *graphql.RawParams) {
+			defer wg.Done()
+			if params == nil {
+				params = &graphql.RawParams{}
+			}
+			params.Headers = r.Header
+			params.ReadTime = graphql.TraceTiming{Start: start, End: graphql.Now()}
+			responses[i] = execute(context.WithValue(ctx, contextKey{}, i), exec, params)
+		}(i, params)
+	}
+	wg.Wait()
+
+	// Encode results one by one, so one that can't be encoded doesn't lose the others
+	results := make([]json.RawMessage, len(responses))
+	for i, resp := range responses {
+		encoded, err := json.Marshal(resp)
+		if err != nil {
+			encoded, _ = json.Marshal(&graphql.Response{Errors: gqlerror.List{gqlerror.Errorf("unable to encode result: %v", err)}})
+		}
+		results[i] = encoded
+	}
+	_ = json.NewEncoder(w).Encode(results)
+}
+
+// execute runs one operation of a batch to completion and returns its result.
+func execute(ctx context.Context, exec graphql.GraphExecutor, params *graphql.RawParams) *graphql.Response {
+	rc, errs := exec.CreateOperationContext(ctx, params)
+	if errs != nil {
+		return exec.DispatchError(graphql.WithOperationContext(ctx, rc), errs)
+	}
+	if rc.Operation.Operation == ast.Subscription {
+		err := gqlerror.Errorf("subscriptions can't be batched, use the websocket transport")
+		return exec.DispatchError(graphql.WithOperationContext(ctx, rc), gqlerror.List{err})
+	}
+	// A batch holds one result per operation, which can't carry the later payloads of @defer
+	if name := incrementalDirective(rc.Operation.SelectionSet, rc.Variables); name != "" {
+		err := gqlerror.Errorf("operations using @%s can't be batched, send them alone accepting multipart/mixed", name)
+		return exec.DispatchError(graphql.WithOperationContext(ctx, rc), gqlerror.List{err})
+	}
+
+	responses, ctx := exec.DispatchOperation(ctx, rc)
+	resp := responses(ctx)
+	if resp == nil {
+		return &graphql.Response{Errors: gqlerror.List{gqlerror.Errorf("operation returned no result")}}
+	}
+	return resp
+}
+
+// incrementalDirective returns the name of the first enabled @defer or @stream directive in a
+// selection set, including its fragments, or "" if there is none.
+func incrementalDirective(set ast.SelectionSet, vars map[string]any) string {
+	for _, sel := range set {
+		var directives ast.DirectiveList
+		var children ast.SelectionSet
+		switch sel := sel.(type) {
+		case *ast.Field:
+			directives, children = sel.Directives, sel.SelectionSet
+		case *ast.InlineFragment:
+			directives, children = sel.Directives, sel.SelectionSet
+		case *ast.FragmentSpread:
+			directives = sel.Directives
+			if sel.Definition != nil {
+				children = sel.Definition.SelectionSet
+			}
+		}
+		for _, name := range []string{"defer", "stream"} {
+			if d := directives.ForName(name); d != nil {
+				if enabled, ok := d.ArgumentMap(vars)["if"].(bool); !ok || enabled {
+					return name
+				}
+			}
+		}
+		if name := incrementalDirective(children, vars); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// writeError writes the response to a request that was rejected as a whole.
+func writeError(w http.ResponseWriter, resp *graphql.Response, status int) {
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,107 @@
+package batching
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph"
+)
+
+// result is one result of a batched response.
+type result struct {
+	Data    map[string]any `json:"data"`
+	Errors  []struct{ Message string }
+	HasNext *bool `json:"hasNext"`
+}
+
+// postBatch sends operations as one batch and returns the results.
+func postBatch(t *testing.T, operations ...string) []result {
+	t.Helper()
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{}))
+	srv.AddTransport(Transport{MaxBatchSize: 10})
+	srv.AddTransport(transport.POST{})
+
+	batch := make([]map[string]string, len(operations))
+	for i, query := range operations {
+		batch[i] = map[string]string{"query": query}
+	}
+	body, _ := json.Marshal(batch)
+	r := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+
+	var results []result
+	if err := json.Unmarshal(w.Body.Bytes(), &results); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+	if len(results) != len(operations) {
+		t.Fatalf("got %d results for %d operations", len(results), len(operations))
+	}
+	return results
+}
+
+func TestIncrementalOperationsRejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		wantError string
+	}{
+		{
+			name:  "plain operation",
+			query: `{ __typename }`,
+		},
+		{
+			name:      "deferred inline fragment",
+			query:     `{ __typename ... @defer { __typename } }`,
+			wantError: "@defer can't be batched",
+		},
+		{
+			name:      "defer in a named fragment",
+			query:     `query { ...Outer } fragment Outer on Query { ... @defer(label: "late") { __typename } }`,
+			wantError: "@defer can't be batched",
+		},
+		{
+			name:  "disabled defer",
+			query: `{ __typename ... @defer(if: false) { __typename } }`,
+		},
+		{
+			name:      "stream",
+			query:     `{ symbols(names: ["AAPL"]) @stream { Name } }`,
+			wantError: "@stream can't be batched",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The other operation of the batch is unaffected
+			results := postBatch(t, tt.query, `{ __typename }`)
+			got := results[0]
+
+			if results[1].Data["__typename"] != "Query" {
+				t.Errorf("other operation = %+v, want its data", results[1])
+			}
+			if got.HasNext != nil && *got.HasNext {
+				t.Errorf("result has hasNext, later payloads would be lost")
+			}
+			if tt.wantError == "" {
+				if len(got.Errors) > 0 || got.Data["__typename"] != "Query" {
+					t.Errorf("result = %+v, want the data", got)
+				}
+				return
+			}
+			if len(got.Errors) != 1 || !strings.Contains(got.Errors[0].Message, tt.wantError) {
+				t.Errorf("errors = %+v, want %q", got.Errors, tt.wantError)
+			}
+			if got.Data != nil {
+				t.Errorf("data = %v, want none", got.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package breaker
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// Closed lets every call through and records the outcomes.
+	Closed State = iota
+	// HalfOpen lets a limited number of probe calls through after the cool-down.
+	HalfOpen
+	// Open rejects every call until the cool-down has passed.
+	Open
+)
+
+// String implements fmt.Stringer.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	case Open:
+		return "open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// windowBuckets is the number of buckets the failure-rate window is divided into.
+const windowBuckets = 10
+
+// Config configures a circuit breaker.
+type Config struct {
+	// Window is the sliding window the failure rate is measured over.
+	Window time.Duration
+	// MinRequests is the number of calls needed in the window before the breaker can trip.
+	MinRequests int
+	// FailureRate is the fraction of failed calls (0 to 1) that trips the breaker.
+	FailureRate float64
+	// CoolDown is how long the breaker stays open before letting probes through.
+	CoolDown time.Duration
+	// HalfOpenProbes is the number of concurrent probe calls allowed while half-open.
+	HalfOpenProbes int
+}
+
+// OpenError is returned when a call is rejected because the breaker is open.
+type OpenError struct {
+	// Name is the name of the rejecting breaker.
+	Name string
+	// RetryAfter is the time left until the breaker lets a probe through.
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s circuit breaker is open, retry after %s", e.Name, e.RetryAfter.Round(time.Second))
+}
+
+// bucket counts the outcomes recorded during one slice of the window.
+type bucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// Breaker is a circuit breaker measuring the failure rate over a sliding window.
+type Breaker struct {
+	name string
+	cfg  Config
+
+	mu       sync.Mutex
+	state    State
+	openedAt time.Time
+	probes   int
+	buckets  [windowBuckets]bucket
+	// generation counts state transitions, so outcomes of calls allowed in an earlier state
+	// can be told apart
+	generation uint64
+}
+
+// Token identifies a call let through by Allow. It is handed back to Record or Release.
+type Token struct {
+	generation uint64
+}
+
+// New creates a closed circuit breaker.
+func New(name string, cfg Config) *Breaker {
+	if cfg.HalfOpenProbes < 1 {
+		cfg.HalfOpenProbes = 1
+	}
+	return &Breaker{name: name, cfg: cfg}
+}
+
+// State returns the current state, moving from open to half-open once the cool-down has passed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkCoolDown(time.Now())
+	return b.state
+}
+
+// Allow reports whether a call may proceed. It returns an *OpenError when the breaker
+// is open, or half-open with all probes in flight. Every allowed call must be followed
+// by exactly one call to Record or Release with the returned token.
+func (b *Breaker) Allow() (Token, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.checkCoolDown(now)
+
+	switch b.state {
+	case Open:
+		return Token{}, &OpenError{Name: b.name, RetryAfter: b.openedAt.Add(b.cfg.CoolDown).Sub(now)}
+	case HalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			return Token{}, &OpenError{Name: b.name}
+		}
+		b.probes++
+	}
+	return Token{generation: b.generation}, nil
+}
+
+// Record reports the outcome of a call let through by Allow. Outcomes of calls allowed
+// before the last state change are ignored: a slow call admitted while closed says nothing
+// about the upstream once the breaker has opened, and must not close it in place of a probe.
+func (b *Breaker) Record(t Token, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.checkCoolDown(now)
+	if t.generation != b.generation {
+		return
+	}
+	switch b.state {
+	case HalfOpen:
+		if b.probes > 0 {
+			b.probes--
+		}
+		if success {
+			b.resetWindow()
+			b.transition(Closed, now)
+		} else {
+			b.transition(Open, now)
+		}
+	case Closed:
+		current := b.bucketFor(now)
+		if success {
+			current.successes++
+		} else {
+			current.failures++
+		}
+		if !success && b.tripped(now) {
+			b.transition(Open, now)
+		}
+	}
+}
+
+// Release ends a call let through by Allow without recording an outcome, for calls
+// cancelled by the caller before the upstream answered, or never made.
+func (b *Breaker) Release(t Token) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t.generation == b.generation && b.state == HalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// checkCoolDown moves an open breaker to half-open once the cool-down has passed.
+// Callers must hold mu.
+func (b *Breaker) checkCoolDown(now time.Time) {
+	if b.state == Open && now.Sub(b.openedAt) >= b.cfg.CoolDown {
+		b.probes = 0
+		b.transition(HalfOpen, now)
+	}
+}
+
+// transition changes the state. Callers must hold mu.
+func (b *Breaker) transition(to State, now time.Time) {
+	from := b.state
+	if from == to {
+		return
+	}
+	b.state = to
+	b.generation++
+	if to == Open {
+		b.openedAt = now
+	}
+	log.Printf("Circuit breaker %s: %s -> %s", b.name, from, to)
+}
+
+// bucketFor returns the window bucket for now, recycling it if it is stale. Callers must hold mu.
+func (b *Breaker) bucketFor(now time.Time) *bucket {
+	width := b.cfg.Window / windowBuckets
+	if width <= 0 {
+		width = time.Second
+	}
+	start := now.Truncate(width)
+	current := &b.buckets[(start.UnixNano()/int64(width))%windowBuckets]
+	if !current.start.Equal(start) {
+		*current = bucket{start: start}
+	}
+	return current
+}
+
+// tripped reports whether the failure rate over the window exceeds the threshold.
+// Callers must hold mu.
+func (b *Breaker) tripped(now time.Time) bool {
+	var successes, failures int
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) < b.cfg.Window {
+			successes += bk.successes
+			failures += bk.failures
+		}
+	}
+	total := successes + failures
+	return total >= b.cfg.MinRequests && float64(failures)/float64(total) >= b.cfg.FailureRate
+}
+
+// resetWindow clears every recorded outcome. Callers must hold mu.
+func (b *Breaker) resetWindow() {
+	b.buckets = [windowBuckets]bucket{}
+}
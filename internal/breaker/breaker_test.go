@@ -0,0 +1,114 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testConfig trips on the first failure and lets one probe through after a short cool-down.
+var testConfig = Config{Window: time.Minute, MinRequests: 1, FailureRate: 0.5, CoolDown: 10 * time.Millisecond, HalfOpenProbes: 1}
+
+// allow calls Allow and fails the test if the call is rejected.
+func allow(t *testing.T, b *Breaker) Token {
+	t.Helper()
+	token, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() = %v, want the call let through", err)
+	}
+	return token
+}
+
+// openThenHalfOpen trips b and waits for its cool-down.
+func openThenHalfOpen(t *testing.T, b *Breaker) {
+	t.Helper()
+	b.Record(allow(t, b), false)
+	if got := b.State(); got != Open {
+		t.Fatalf("state = %s after a failure, want open", got)
+	}
+	time.Sleep(2 * testConfig.CoolDown)
+	if got := b.State(); got != HalfOpen {
+		t.Fatalf("state = %s after the cool-down, want half-open", got)
+	}
+}
+
+func TestBreakerIgnoresCallsAllowedBeforeOpening(t *testing.T) {
+	tests := []struct {
+		name    string
+		success bool
+	}{
+		{name: "late success doesn't close", success: true},
+		{name: "late failure doesn't reopen", success: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("test", testConfig)
+			slow := allow(t, b)
+			openThenHalfOpen(t, b)
+			probe := allow(t, b)
+
+			// The call admitted while closed finishes during half-open, before the probe
+			b.Record(slow, tt.success)
+			if got := b.State(); got != HalfOpen {
+				t.Fatalf("state = %s after a stale outcome, want half-open", got)
+			}
+			var openErr *OpenError
+			if _, err := b.Allow(); !errors.As(err, &openErr) {
+				t.Fatalf("Allow() = %v, want the probe slot still taken", err)
+			}
+
+			// Only the probe decides
+			b.Record(probe, true)
+			if got := b.State(); got != Closed {
+				t.Errorf("state = %s after the probe succeeded, want closed", got)
+			}
+		})
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		success bool
+		want    State
+	}{
+		{name: "successful probe closes", success: true, want: Closed},
+		{name: "failed probe reopens", success: false, want: Open},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New("test", testConfig)
+			openThenHalfOpen(t, b)
+			b.Record(allow(t, b), tt.success)
+			if got := b.State(); got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerReleaseFreesProbe(t *testing.T) {
+	b := New("test", testConfig)
+	openThenHalfOpen(t, b)
+
+	b.Release(allow(t, b))
+	if got := b.State(); got != HalfOpen {
+		t.Fatalf("state = %s after a release, want half-open", got)
+	}
+	// The released slot is free for the next probe
+	allow(t, b)
+}
+
+func TestBreakerStaleReleaseKeepsProbe(t *testing.T) {
+	b := New("test", testConfig)
+	slow := allow(t, b)
+	openThenHalfOpen(t, b)
+	allow(t, b)
+
+	b.Release(slow)
+	if _, err := b.Allow(); err == nil {
+		t.Error("Allow() let a second probe through after a stale release")
+	}
+}
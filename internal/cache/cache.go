@@ -9,38 +9,106 @@ import (
 const (
 	defaultTTL      = 5 * time.Minute
 	cleanupInterval = 10 * time.Minute
+	// staleTTL is how long the last known value is kept after it expired from the
+	// shared cache, to be served while the upstream is unavailable.
+	staleTTL = 24 * time.Hour
 )
 
 // sharedCache holds the single instance of our memory cache.
 var sharedCache *gocache.Cache
 
+// staleCache keeps the last known value for every key well past its freshness TTL.
+var staleCache *gocache.Cache
+
 // init initializes the shared cache when the package is first used.
 func init() {
 	sharedCache = gocache.New(defaultTTL, cleanupInterval)
+	staleCache = gocache.New(staleTTL, cleanupInterval)
+}
+
+// Namespace is a typed section of the shared cache. Keys are prefixed with the namespace
+// name, so different kinds of data cached for the same symbol don't collide.
+type Namespace[V any] struct {
+	prefix string
+	// ttl is how long items stay fresh. Zero uses the default cache TTL.
+	ttl time.Duration
 }
 
-// Set adds an item to the cache, replacing any existing item.
-// It uses the default cache TTL.
-func Set(key string, value *time.Time) {
-	if value == nil { // Avoid caching nil pointers explicitly, though go-cache might handle it
-		return
+// NewNamespace returns the namespace with the given name.
+func NewNamespace[V any](name string) Namespace[V] {
+	return Namespace[V]{prefix: name + ":"}
+}
+
+// WithTTL returns the namespace with items staying fresh for ttl instead of the default TTL.
+func (n Namespace[V]) WithTTL(ttl time.Duration) Namespace[V] {
+	n.ttl = ttl
+	return n
+}
+
+// Set adds an item to the namespace, replacing any existing item.
+// It uses the namespace TTL, the default cache TTL unless set with WithTTL.
+func (n Namespace[V]) Set(key string, value V) {
+	ttl := gocache.DefaultExpiration
+	if n.ttl > 0 {
+		ttl = n.ttl
 	}
-	sharedCache.Set(key, value, gocache.DefaultExpiration)
+	sharedCache.Set(n.prefix+key, value, ttl)
+	staleCache.Set(n.prefix+key, value, gocache.DefaultExpiration)
+}
+
+// Get retrieves an item from the namespace.
+// It returns the item and a bool indicating whether the key was found.
+func (n Namespace[V]) Get(key string) (V, bool) {
+	return lookup[V](sharedCache, n.prefix+key)
+}
+
+// GetStale retrieves the last known value for a key, even if it has expired from the
+// shared cache. It is meant as a fallback when the upstream can't be reached.
+func (n Namespace[V]) GetStale(key string) (V, bool) {
+	return lookup[V](staleCache, n.prefix+key)
 }
 
-// Get retrieves an item from the cache.
-// It returns the item or nil, and a bool indicating whether the key was found.
-func Get(key string) (*time.Time, bool) {
-	val, found := sharedCache.Get(key)
+// TTL returns how long an item stays fresh in the namespace, and whether it is there.
+func (n Namespace[V]) TTL(key string) (time.Duration, bool) {
+	_, expiration, found := sharedCache.GetWithExpiration(n.prefix + key)
 	if !found {
-		return nil, false
+		return 0, false
+	}
+	if expiration.IsZero() {
+		return defaultTTL, true
+	}
+	return time.Until(expiration), true
+}
+
+// Delete removes an item from the namespace, including its last known value, so the next
+// lookup goes to the upstream and a wrong value can't come back as a stale fallback.
+func (n Namespace[V]) Delete(key string) {
+	sharedCache.Delete(n.prefix + key)
+	staleCache.Delete(n.prefix + key)
+}
+
+// Flush removes every item of every namespace, including the last known values.
+func Flush() {
+	sharedCache.Flush()
+	staleCache.Flush()
+}
+
+// lookup retrieves an item of type V from c, treating items of another type as not found.
+func lookup[V any](c *gocache.Cache, key string) (V, bool) {
+	var zero V
+	val, found := c.Get(key)
+	if !found {
+		return zero, false
 	}
 
 	// Type assertion to ensure we return the correct type
-	cachedTime, ok := val.(*time.Time)
+	typed, ok := val.(V)
 	if !ok {
-		// Item found but is not the expected type, treat as not found
-		return nil, false
+		return zero, false
 	}
-	return cachedTime, true
+	return typed, true
 }
+
+// DividendDates holds the next ex-dividend dates. It predates namespaces, so its keys
+// are the bare symbols.
+var DividendDates = Namespace[*time.Time]{}
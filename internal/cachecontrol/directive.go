@@ -0,0 +1,45 @@
+package cachecontrol
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// directiveName is the name of the schema directive carrying cache hints.
+const directiveName = "cacheControl"
+
+// Directive implements the @cacheControl(maxAge: ..., scope: ...) schema directive. Each
+// resolved field carrying it lowers the max-age of the response to its own, in seconds.
+func Directive(ctx context.Context, obj any, next graphql.Resolver, maxAge *int32, scope *Scope) (any, error) {
+	hint := time.Duration(0)
+	if maxAge != nil {
+		hint = time.Duration(*maxAge) * time.Second
+	}
+	hintScope := Public
+	if scope != nil {
+		hintScope = *scope
+	}
+	Restrict(ctx, hint, hintScope)
+	return next(ctx)
+}
+
+// AroundRootFields makes responses with a root field lacking a @cacheControl hint
+// uncacheable. Hints on nested fields are optional: they inherit their parent's.
+func AroundRootFields(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+	field := graphql.GetRootFieldContext(ctx).Field
+	if field.Definition == nil || field.Definition.Directives.ForName(directiveName) == nil {
+		Restrict(ctx, 0, Public)
+	}
+	return next(ctx)
+}
+
+// AroundResponses makes responses carrying errors uncacheable.
+func AroundResponses(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if resp != nil && len(resp.Errors) > 0 {
+		Restrict(ctx, 0, Public)
+	}
+	return resp
+}
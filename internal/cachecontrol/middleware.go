@@ -0,0 +1,94 @@
+package cachecontrol
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Middleware serves GET queries with a Cache-Control header computed from the hints of the
+// fields resolved, and an ETag of the response body. Requests whose If-None-Match matches
+// the ETag get 304 Not Modified without a body. Other requests pass through untouched.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Websocket upgrades are GET requests too, but need the connection itself
+		if r.Method != http.MethodGet || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, policy := WithPolicy(r.Context())
+		buffered := &bufferedWriter{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(buffered, r.WithContext(ctx))
+
+		header := w.Header()
+		for name, values := range buffered.header {
+			header[name] = values
+		}
+		// Responses depend on the credentials, since fields require roles
+		header.Add("Vary", "Authorization, X-API-Key")
+		if buffered.status != http.StatusOK {
+			header.Set("Cache-Control", "no-store")
+			w.WriteHeader(buffered.status)
+			_, _ = w.Write(buffered.body.Bytes())
+			return
+		}
+
+		etag := entityTag(buffered.body.Bytes())
+		header.Set("Cache-Control", policy.Header())
+		header.Set("ETag", etag)
+		if matches(r.Header.Get("If-None-Match"), etag) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(buffered.body.Bytes())
+	})
+}
+
+// entityTag returns a strong ETag for a response body.
+func entityTag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matches reports whether an If-None-Match header matches etag, using the weak comparison
+// required for GET requests.
+func matches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedWriter holds a response until its headers can be completed from the body.
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// Header implements http.ResponseWriter.
+func (b *bufferedWriter) Header() http.Header {
+	return b.header
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (b *bufferedWriter) WriteHeader(status int) {
+	b.status = status
+}
+
+// Write implements http.ResponseWriter.
+func (b *bufferedWriter) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
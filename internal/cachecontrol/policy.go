@@ -0,0 +1,121 @@
+// Package cachecontrol computes how long a query response may be cached from the
+// @cacheControl hints of the fields it resolved, and serves GET queries with Cache-Control
+// and ETag headers so clients and shared caches can reuse them.
+package cachecontrol
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Scope says who may cache a response: any cache, or only the client's own.
+type Scope string
+
+const (
+	Public  Scope = "PUBLIC"
+	Private Scope = "PRIVATE"
+)
+
+// MarshalGQL implements graphql.Marshaler for the CacheControlScope enum.
+func (s Scope) MarshalGQL(w io.Writer) {
+	_, _ = io.WriteString(w, strconv.Quote(string(s)))
+}
+
+// UnmarshalGQL implements graphql.Unmarshaler for the CacheControlScope enum.
+func (s *Scope) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("CacheControlScope must be a string")
+	}
+	switch scope := Scope(str); scope {
+	case Public, Private:
+		*s = scope
+		return nil
+	default:
+		return fmt.Errorf("%s is not a valid CacheControlScope", str)
+	}
+}
+
+// Policy accumulates the cache hints of the fields resolved for one response. The response
+// may be cached for the smallest max-age hinted, and only privately if any field says so.
+// It is safe for concurrent use, since fields resolve concurrently.
+type Policy struct {
+	mu     sync.Mutex
+	hinted bool
+	maxAge time.Duration
+	scope  Scope
+}
+
+type contextKey struct{}
+
+// WithPolicy attaches a new policy to ctx, collecting the hints of the response resolved with it.
+func WithPolicy(ctx context.Context) (context.Context, *Policy) {
+	p := &Policy{scope: Public}
+	return context.WithValue(ctx, contextKey{}, p), p
+}
+
+// FromContext returns the policy attached to ctx, or nil when the response isn't cacheable.
+func FromContext(ctx context.Context) *Policy {
+	p, _ := ctx.Value(contextKey{}).(*Policy)
+	return p
+}
+
+// Restrict lowers the max-age of the response being resolved to maxAge, and makes it private
+// when scope is Private. Resolvers use it for hints only known once the data is loaded.
+// It does nothing for responses that are never cached, like POST queries.
+func Restrict(ctx context.Context, maxAge time.Duration, scope Scope) {
+	p := FromContext(ctx)
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.hinted || maxAge < p.maxAge {
+		p.maxAge = max(maxAge, 0)
+	}
+	p.hinted = true
+	if scope == Private {
+		p.scope = Private
+	}
+}
+
+// MakePrivate makes the response being resolved private, keeping its max-age. It is used for
+// responses resolved for an authenticated principal, which shared caches must not reuse.
+// It does nothing for responses that are never cached.
+func MakePrivate(ctx context.Context) {
+	p := FromContext(ctx)
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scope = Private
+}
+
+// MaxAge returns how long the response may be cached and by whom. Responses without any
+// hint aren't cacheable.
+func (p *Policy) MaxAge() (time.Duration, Scope) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.hinted {
+		return 0, p.scope
+	}
+	return p.maxAge, p.scope
+}
+
+// Header returns the Cache-Control header value for the policy.
+func (p *Policy) Header() string {
+	maxAge, scope := p.MaxAge()
+	seconds := int64(maxAge / time.Second)
+	if seconds <= 0 {
+		return "no-store"
+	}
+	if scope == Private {
+		return fmt.Sprintf("private, max-age=%d", seconds)
+	}
+	return fmt.Sprintf("public, max-age=%d", seconds)
+}
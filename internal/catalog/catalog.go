@@ -0,0 +1,119 @@
+// Package catalog holds the symbol reference data: what each ticker is, where it trades
+// and in which currency. It is loaded once from a local file and indexed in memory.
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/symbols"
+)
+
+// AssetType classifies a security.
+type AssetType string
+
+const (
+	AssetCommonStock AssetType = "COMMON_STOCK"
+	AssetETF         AssetType = "ETF"
+	AssetADR         AssetType = "ADR"
+	AssetFund        AssetType = "FUND"
+)
+
+// valid reports whether t is one of the known asset types.
+func (t AssetType) valid() bool {
+	switch t {
+	case AssetCommonStock, AssetETF, AssetADR, AssetFund:
+		return true
+	}
+	return false
+}
+
+// Entry is the reference data of one symbol.
+type Entry struct {
+	Symbol      string    `json:"symbol"`
+	Description string    `json:"description"`
+	Exchange    string    `json:"exchange"`
+	AssetType   AssetType `json:"assetType"`
+	Currency    string    `json:"currency"`
+	ISIN        string    `json:"isin"`
+}
+
+// Catalog is an in-memory index of symbol reference data. It is read-only once loaded.
+type Catalog struct {
+	bySymbol map[string]*Entry
+	// sorted holds every entry ordered by symbol, for prefix lookups.
+	sorted []*Entry
+	// words holds every word of every description ordered alphabetically, for prefix lookups.
+	words []word
+}
+
+// word is one lowercased word of an entry's description.
+type word struct {
+	text  string
+	entry *Entry
+}
+
+// Load reads a JSON array of entries from path and indexes it.
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return New(entries)
+}
+
+// New indexes entries. Symbols are normalised, and must be valid and unique.
+func New(entries []Entry) (*Catalog, error) {
+	c := &Catalog{bySymbol: make(map[string]*Entry, len(entries))}
+	for i := range entries {
+		entry := &entries[i]
+		symbol, err := symbols.Normalize(entry.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("catalog entry %d: %w", i, err)
+		}
+		if !entry.AssetType.valid() {
+			return nil, fmt.Errorf("catalog entry %d: unknown asset type %q", i, entry.AssetType)
+		}
+		if _, dup := c.bySymbol[symbol]; dup {
+			return nil, fmt.Errorf("catalog entry %d: duplicate symbol %s", i, symbol)
+		}
+		entry.Symbol = symbol
+		c.bySymbol[symbol] = entry
+		c.sorted = append(c.sorted, entry)
+		for _, text := range descriptionWords(entry.Description) {
+			c.words = append(c.words, word{text: text, entry: entry})
+		}
+	}
+
+	sort.Slice(c.sorted, func(i, j int) bool { return c.sorted[i].Symbol < c.sorted[j].Symbol })
+	sort.Slice(c.words, func(i, j int) bool { return c.words[i].text < c.words[j].text })
+	return c, nil
+}
+
+// Len returns the number of entries.
+func (c *Catalog) Len() int {
+	return len(c.sorted)
+}
+
+// Lookup returns the entry of a normalised symbol, or nil if it is unknown.
+func (c *Catalog) Lookup(symbol string) *Entry {
+	if c == nil {
+		return nil
+	}
+	return c.bySymbol[symbol]
+}
+
+// descriptionWords splits a description into lowercased words, dropping punctuation.
+func descriptionWords(description string) []string {
+	return strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
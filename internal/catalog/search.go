@@ -0,0 +1,128 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+)
+
+// Match ranks, best first. Within a rank, shorter symbols come first, then alphabetical order.
+const (
+	rankExact = iota
+	rankSymbolPrefix
+	rankDescriptionPrefix
+	rankFuzzy
+)
+
+// minFuzzyLength is the shortest query matched fuzzily.
+const minFuzzyLength = 3
+
+// Search returns up to limit entries matching query, best matches first: the exact symbol,
+// symbols starting with query, descriptions with a word starting with query, and finally
+// symbols within a small edit distance of query, to forgive typos.
+func (c *Catalog) Search(query string, limit int) []*Entry {
+	query = strings.TrimSpace(query)
+	if c == nil || query == "" || limit <= 0 {
+		return nil
+	}
+	upper, lower := strings.ToUpper(query), strings.ToLower(query)
+
+	type match struct {
+		entry *Entry
+		rank  int
+		// distance orders fuzzy matches
+		distance int
+	}
+	best := make(map[*Entry]match)
+	consider := func(m match) {
+		if current, seen := best[m.entry]; !seen || m.rank < current.rank {
+			best[m.entry] = m
+		}
+	}
+
+	// Symbol prefixes are a contiguous run of the sorted symbols
+	start := sort.Search(len(c.sorted), func(i int) bool { return c.sorted[i].Symbol >= upper })
+	for i := start; i < len(c.sorted) && strings.HasPrefix(c.sorted[i].Symbol, upper); i++ {
+		rank := rankSymbolPrefix
+		if c.sorted[i].Symbol == upper {
+			rank = rankExact
+		}
+		consider(match{entry: c.sorted[i], rank: rank})
+	}
+
+	// And so are description word prefixes
+	start = sort.Search(len(c.words), func(i int) bool { return c.words[i].text >= lower })
+	for i := start; i < len(c.words) && strings.HasPrefix(c.words[i].text, lower); i++ {
+		consider(match{entry: c.words[i].entry, rank: rankDescriptionPrefix})
+	}
+
+	// Fuzzy matching scans every symbol, which is fine for a reference catalog. Any one or two
+	// letter symbol is a typo away from the shortest queries, so they don't match fuzzily.
+	if len(upper) >= minFuzzyLength {
+		maxDistance := 1
+		if len(upper) > 4 {
+			maxDistance = 2
+		}
+		for _, entry := range c.sorted {
+			if _, seen := best[entry]; seen {
+				continue
+			}
+			if d := editDistance(upper, entry.Symbol); d <= maxDistance {
+				consider(match{entry: entry, rank: rankFuzzy, distance: d})
+			}
+		}
+	}
+
+	matches := make([]match, 0, len(best))
+	for _, m := range best {
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case a.rank != b.rank:
+			return a.rank < b.rank
+		case a.distance != b.distance:
+			return a.distance < b.distance
+		case len(a.entry.Symbol) != len(b.entry.Symbol):
+			return len(a.entry.Symbol) < len(b.entry.Symbol)
+		default:
+			return a.entry.Symbol < b.entry.Symbol
+		}
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]*Entry, len(matches))
+	for i, m := range matches {
+		result[i] = m.entry
+	}
+	return result
+}
+
+// editDistance returns the Levenshtein distance between a and b, counting a swap of two
+// adjacent characters as one edit.
+func editDistance(a, b string) int {
+	// Three rows of the dynamic programming table are enough
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPort = "8080"
+
+// Config holds the server settings read from the environment at startup.
+type Config struct {
+	// Port is the HTTP port the server listens on (PORT).
+	Port string
+
+	// AllowedOrigins lists the origins allowed to open websocket connections
+	// (ALLOWED_ORIGINS, comma separated). An empty list only allows same-origin
+	// requests; "*" allows every origin.
+	AllowedOrigins []string
+
+	// APIKeys maps static API keys to their owner (API_KEYS, comma separated
+	// "key=subject" or "key=subject:role1|role2" pairs).
+	APIKeys map[string]string
+
+	// JWTSecret is the shared secret used to verify HS256 tokens (JWT_HS256_SECRET).
+	JWTSecret string
+
+	// JWTPublicKeyFile is the path of a PEM encoded RSA public key or certificate
+	// used to verify RS256 tokens (JWT_RS256_PUBLIC_KEY_FILE).
+	JWTPublicKeyFile string
+
+	// JWTIssuer and JWTAudience, when set, must match the iss and aud claims
+	// (JWT_ISSUER, JWT_AUDIENCE).
+	JWTIssuer   string
+	JWTAudience string
+
+	// RateLimitRPS and RateLimitBurst configure the per-client token bucket
+	// (RATE_LIMIT_RPS, RATE_LIMIT_BURST). A rate of 0 disables client rate limiting.
+	RateLimitRPS   float64
+	RateLimitBurst int
+
+	// UpstreamQuotaRPS and UpstreamQuotaBurst configure the global budget of calls to
+	// the dividend date upstream (UPSTREAM_QUOTA_RPS, UPSTREAM_QUOTA_BURST).
+	// A rate of 0 disables the quota.
+	UpstreamQuotaRPS   float64
+	UpstreamQuotaBurst int
+
+	// UpstreamQuotaMaxWait is how long a batch queues for the upstream budget before
+	// it is rejected (UPSTREAM_QUOTA_MAX_WAIT). Zero rejects immediately.
+	UpstreamQuotaMaxWait time.Duration
+
+	// RetryMaxAttempts is the total number of upstream calls per key, including the
+	// first (RETRY_MAX_ATTEMPTS). 1 disables retries.
+	RetryMaxAttempts int
+
+	// RetryInitialBackoff and RetryMaxBackoff bound the exponential backoff between
+	// retries (RETRY_INITIAL_BACKOFF, RETRY_MAX_BACKOFF).
+	RetryInitialBackoff time.Duration
+	RetryMaxBackoff     time.Duration
+
+	// RetryJitter randomises each backoff by up to this fraction (RETRY_JITTER).
+	RetryJitter float64
+
+	// BreakerFailureRate is the fraction of failed upstream calls that opens the
+	// circuit breaker (BREAKER_FAILURE_RATE). 0 disables the breaker.
+	BreakerFailureRate float64
+
+	// BreakerMinRequests is the number of calls in the window needed before the
+	// breaker can open (BREAKER_MIN_REQUESTS).
+	BreakerMinRequests int
+
+	// BreakerWindow is the sliding window the failure rate is measured over (BREAKER_WINDOW).
+	BreakerWindow time.Duration
+
+	// BreakerCoolDown is how long the breaker stays open before probing the
+	// upstream again (BREAKER_COOLDOWN).
+	BreakerCoolDown time.Duration
+
+	// FieldTimeout is how long loader-backed fields wait before resolving to null with
+	// a TIMEOUT error (FIELD_TIMEOUT). 0 disables it.
+	FieldTimeout time.Duration
+
+	// UpstreamTimeout bounds how long a batch may wait on the upstream, including
+	// retries (UPSTREAM_TIMEOUT). Batches keep running after a field timed out.
+	UpstreamTimeout time.Duration
+
+	// BatchWait is how long dataloaders collect keys before calling the upstream (LOADER_BATCH_WAIT).
+	BatchWait time.Duration
+
+	// MultiFieldLoader fetches nextDividend, nextEarningsDate, nextSplit and splits through
+	// one shared loader and one combined upstream call (MULTI_FIELD_LOADER).
+	MultiFieldLoader bool
+
+	// HedgePercentile is the latency percentile (0 to 1) after which a slow upstream
+	// call is duplicated (HEDGE_PERCENTILE). 0 disables hedging.
+	HedgePercentile float64
+
+	// HedgeMinDelay is the shortest delay before a hedge is issued (HEDGE_MIN_DELAY).
+	HedgeMinDelay time.Duration
+
+	// HedgeMaxRate caps the fraction of upstream calls that may be hedged (HEDGE_MAX_RATE).
+	HedgeMaxRate float64
+
+	// FaultInjectionEnabled wraps the upstream with a fault injecting source and serves
+	// its runtime configuration on /admin/faults (FAULT_INJECTION_ENABLED).
+	FaultInjectionEnabled bool
+
+	// FaultInjection is the initial fault configuration as JSON (FAULT_INJECTION),
+	// e.g. {"keyErrorRate":0.2,"spikeRate":0.05,"spikeLatency":"3s"}.
+	FaultInjection string
+
+	// SymbolCatalogFile is the JSON file the symbol reference catalog is loaded from
+	// (SYMBOL_CATALOG_FILE).
+	SymbolCatalogFile string
+
+	// AuditLogFile receives the audit trail of admin mutations as JSON lines (AUDIT_LOG_FILE).
+	// Empty writes it to the server log.
+	AuditLogFile string
+
+	// ResponseCacheEnabled serves repeated queries from a cache of whole responses, kept for
+	// the max-age of their @cacheControl hints (RESPONSE_CACHE_ENABLED).
+	ResponseCacheEnabled bool
+
+	// APQStore is where automatic persisted queries are kept (APQ_STORE): "lru" for a
+	// dedicated LRU, or "cache" for a namespace of the shared cache.
+	APQStore string
+	// APQCacheSize is how many automatic persisted queries the LRU store keeps (APQ_CACHE_SIZE).
+	APQCacheSize int
+	// APQCacheTTL is how long the shared cache store keeps a query registered (APQ_CACHE_TTL).
+	APQCacheTTL time.Duration
+
+	// PersistedQueryManifest is a manifest of the operations clients may execute
+	// (PERSISTED_QUERY_MANIFEST). When set, every other operation is rejected and
+	// automatic persisted queries can't be registered.
+	PersistedQueryManifest string
+
+	// MaxBatchSize is the largest number of operations accepted in one batched POST request
+	// (MAX_BATCH_SIZE). 0 disables batching.
+	MaxBatchSize int
+}
+
+// AuthEnabled reports whether any authentication method is configured.
+// Authentication is disabled when neither API keys nor JWT keys are set.
+func (c Config) AuthEnabled() bool {
+	return len(c.APIKeys) > 0 || c.JWTEnabled()
+}
+
+// JWTEnabled reports whether JWT verification keys are configured.
+func (c Config) JWTEnabled() bool {
+	return c.JWTSecret != "" || c.JWTPublicKeyFile != ""
+}
+
+// Load reads the configuration from environment variables, applying defaults
+// for anything that is not set.
+func Load() Config {
+	cfg := Config{
+		Port:           getEnv("PORT", defaultPort),
+		AllowedOrigins: splitList(os.Getenv("ALLOWED_ORIGINS")),
+		APIKeys:        parsePairs("API_KEYS", os.Getenv("API_KEYS")),
+
+		JWTSecret:        os.Getenv("JWT_HS256_SECRET"),
+		JWTPublicKeyFile: os.Getenv("JWT_RS256_PUBLIC_KEY_FILE"),
+		JWTIssuer:        os.Getenv("JWT_ISSUER"),
+		JWTAudience:      os.Getenv("JWT_AUDIENCE"),
+
+		RateLimitRPS:   getFloat("RATE_LIMIT_RPS", 10),
+		RateLimitBurst: getInt("RATE_LIMIT_BURST", 20),
+
+		UpstreamQuotaRPS:     getFloat("UPSTREAM_QUOTA_RPS", 5),
+		UpstreamQuotaBurst:   getInt("UPSTREAM_QUOTA_BURST", 5),
+		UpstreamQuotaMaxWait: getDuration("UPSTREAM_QUOTA_MAX_WAIT", time.Second),
+
+		RetryMaxAttempts:    getInt("RETRY_MAX_ATTEMPTS", 3),
+		RetryInitialBackoff: getDuration("RETRY_INITIAL_BACKOFF", 100*time.Millisecond),
+		RetryMaxBackoff:     getDuration("RETRY_MAX_BACKOFF", 2*time.Second),
+		RetryJitter:         getFloat("RETRY_JITTER", 0.2),
+
+		BreakerFailureRate: getFloat("BREAKER_FAILURE_RATE", 0.5),
+		BreakerMinRequests: getInt("BREAKER_MIN_REQUESTS", 5),
+		BreakerWindow:      getDuration("BREAKER_WINDOW", 30*time.Second),
+		BreakerCoolDown:    getDuration("BREAKER_COOLDOWN", 15*time.Second),
+
+		FieldTimeout:    getDuration("FIELD_TIMEOUT", 2*time.Second),
+		UpstreamTimeout: getDuration("UPSTREAM_TIMEOUT", 10*time.Second),
+		BatchWait:       getDuration("LOADER_BATCH_WAIT", 16*time.Millisecond),
+
+		MultiFieldLoader: getBool("MULTI_FIELD_LOADER", false),
+
+		HedgePercentile: getFloat("HEDGE_PERCENTILE", 0),
+		HedgeMinDelay:   getDuration("HEDGE_MIN_DELAY", 50*time.Millisecond),
+		HedgeMaxRate:    getFloat("HEDGE_MAX_RATE", 0.1),
+
+		FaultInjectionEnabled: getBool("FAULT_INJECTION_ENABLED", false),
+		FaultInjection:        os.Getenv("FAULT_INJECTION"),
+
+		SymbolCatalogFile: getEnv("SYMBOL_CATALOG_FILE", "data/symbols.json"),
+		AuditLogFile:      os.Getenv("AUDIT_LOG_FILE"),
+
+		ResponseCacheEnabled: getBool("RESPONSE_CACHE_ENABLED", false),
+
+		APQStore:               getEnv("APQ_STORE", "lru"),
+		APQCacheSize:           getInt("APQ_CACHE_SIZE", 1000),
+		APQCacheTTL:            getDuration("APQ_CACHE_TTL", 24*time.Hour),
+		PersistedQueryManifest: os.Getenv("PERSISTED_QUERY_MANIFEST"),
+
+		MaxBatchSize: getInt("MAX_BATCH_SIZE", 10),
+	}
+	return cfg
+}
+
+// getEnv returns the value of the environment variable or the fallback if unset.
+func getEnv(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getInt parses an integer environment variable, logging and falling back on bad input.
+func getInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", name, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// getBool parses a boolean environment variable, logging and falling back on bad input.
+func getBool(name string, fallback bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %t", name, value, fallback)
+		return fallback
+	}
+	return b
+}
+
+// getFloat parses a float environment variable, logging and falling back on bad input.
+func getFloat(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %g", name, value, fallback)
+		return fallback
+	}
+	return f
+}
+
+// getDuration parses a duration environment variable such as "500ms",
+// logging and falling back on bad input.
+func getDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", name, value, fallback)
+		return fallback
+	}
+	return d
+}
+
+// splitList splits a comma separated list, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parsePairs parses a comma separated list of "key=value" pairs.
+// Malformed entries are logged and skipped.
+func parsePairs(name, value string) map[string]string {
+	pairs := make(map[string]string)
+	for i, item := range splitList(value) {
+		key, val, ok := strings.Cut(item, "=")
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if !ok || key == "" || val == "" {
+			// Don't echo the entry itself, it may contain a secret.
+			log.Printf("Ignoring malformed %s entry #%d", name, i+1)
+			continue
+		}
+		pairs[key] = val
+	}
+	return pairs
+}
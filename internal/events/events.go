@@ -0,0 +1,82 @@
+// Package events notifies subscriptions that the data of a symbol changed outside of the
+// upstream, e.g. because an operator overrode or invalidated it.
+package events
+
+import (
+	"log"
+	"sync"
+)
+
+// Change is published when the data of a symbol changed.
+type Change struct {
+	// Symbol is the normalised symbol that changed. Empty means every symbol.
+	Symbol string
+	// Reason describes the change, e.g. "override".
+	Reason string
+}
+
+// subscriberBuffer is how many changes a subscriber may fall behind before changes are dropped.
+const subscriberBuffer = 16
+
+// subscriber receives the changes of a set of symbols.
+type subscriber struct {
+	symbols map[string]bool
+	ch      chan Change
+}
+
+var (
+	mu          sync.Mutex
+	subscribers = make(map[*subscriber]struct{})
+	listeners   []func(Change)
+)
+
+// Listen calls fn synchronously with every change published, before Publish returns.
+// Unlike subscribers, listeners never miss a change, so they suit cache invalidation.
+// It should be called at startup, before the server accepts requests.
+func Listen(fn func(Change)) {
+	mu.Lock()
+	defer mu.Unlock()
+	listeners = append(listeners, fn)
+}
+
+// Subscribe returns a channel receiving the changes of symbols, and a function that
+// unsubscribes and closes the channel. Changes are dropped for subscribers that fall behind.
+func Subscribe(symbols []string) (<-chan Change, func()) {
+	sub := &subscriber{symbols: make(map[string]bool, len(symbols)), ch: make(chan Change, subscriberBuffer)}
+	for _, symbol := range symbols {
+		sub.symbols[symbol] = true
+	}
+
+	mu.Lock()
+	subscribers[sub] = struct{}{}
+	mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			mu.Lock()
+			delete(subscribers, sub)
+			mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
+
+// Publish notifies the subscribers of a symbol, or every subscriber for a change of every symbol.
+func Publish(change Change) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, fn := range listeners {
+		fn(change)
+	}
+	for sub := range subscribers {
+		if change.Symbol != "" && !sub.symbols[change.Symbol] {
+			continue
+		}
+		select {
+		case sub.ch <- change:
+		default:
+			log.Printf("Dropping %s change of %q for a slow subscriber", change.Reason, change.Symbol)
+		}
+	}
+}
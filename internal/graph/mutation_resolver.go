@@ -0,0 +1,27 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph/model"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/resolvers"
+)
+
+// mutationResolver implements the generated MutationResolver interface.
+type mutationResolver struct{ *Resolver }
+
+// InvalidateDividendDate delegates the Mutation.invalidateDividendDate field resolution.
+func (r *mutationResolver) InvalidateDividendDate(ctx context.Context, names []string) ([]string, error) {
+	return resolvers.InvalidateDividendDateImpl(ctx, names)
+}
+
+// InvalidateAllCaches delegates the Mutation.invalidateAllCaches field resolution.
+func (r *mutationResolver) InvalidateAllCaches(ctx context.Context) (bool, error) {
+	return resolvers.InvalidateAllCachesImpl(ctx)
+}
+
+// OverrideDividendDate delegates the Mutation.overrideDividendDate field resolution.
+func (r *mutationResolver) OverrideDividendDate(ctx context.Context, name string, date time.Time, expiresAt *time.Time) (*model.DividendDateOverride, error) {
+	return resolvers.OverrideDividendDateImpl(ctx, name, date, expiresAt)
+}
@@ -14,3 +14,13 @@ type queryResolver struct{ *Resolver }
 func (r *queryResolver) Symbols(ctx context.Context, names []string) ([]*model.SymbolDefinition, error) {
 	return resolvers.SymbolsImpl(ctx, names)
 }
+
+// Symbol delegates the Query.symbol field resolution.
+func (r *queryResolver) Symbol(ctx context.Context, name string) (*model.SymbolDefinition, error) {
+	return resolvers.SymbolImpl(ctx, name)
+}
+
+// SearchSymbols delegates the Query.searchSymbols field resolution.
+func (r *queryResolver) SearchSymbols(ctx context.Context, prefix string, first *int32) ([]*model.SymbolDefinition, error) {
+	return resolvers.SearchSymbolsImpl(ctx, prefix, first)
+}
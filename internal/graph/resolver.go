@@ -27,6 +27,11 @@ func (r *Resolver) Query() generatedGraph.QueryResolver { // Use generated inter
 	return &queryResolver{r}
 }
 
+// Mutation returns the mutation resolver implementation satisfying generatedGraph.MutationResolver.
+func (r *Resolver) Mutation() generatedGraph.MutationResolver { // Use generated interface type
+	return &mutationResolver{r}
+}
+
 // Subscription returns the subscription resolver implementation satisfying generatedGraph.SubscriptionResolver.
 func (r *Resolver) Subscription() generatedGraph.SubscriptionResolver { // Use generated interface type
 	return &subscriptionResolver{r}
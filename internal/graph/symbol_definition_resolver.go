@@ -16,3 +16,33 @@ func (r *symbolDefinitionResolver) NextExDividendDate(ctx context.Context, obj *
 	// Delegate to our custom implementation
 	return resolvers.NextExDividendDate(ctx, obj, singleFlight)
 }
+
+// NextExDividendDateResult delegates the SymbolDefinition.nextExDividendDateResult field resolution.
+func (r *symbolDefinitionResolver) NextExDividendDateResult(ctx context.Context, obj *model.SymbolDefinition, singleFlight *bool) (*model.DividendDateResult, error) {
+	return resolvers.NextExDividendDateResult(ctx, obj, singleFlight)
+}
+
+// NextDividend delegates the SymbolDefinition.nextDividend field resolution.
+func (r *symbolDefinitionResolver) NextDividend(ctx context.Context, obj *model.SymbolDefinition, singleFlight *bool) (*model.Dividend, error) {
+	return resolvers.NextDividend(ctx, obj, singleFlight)
+}
+
+// DividendHistory delegates the SymbolDefinition.dividendHistory field resolution.
+func (r *symbolDefinitionResolver) DividendHistory(ctx context.Context, obj *model.SymbolDefinition, from *time.Time, to *time.Time, first *int32, after *string, last *int32, before *string, singleFlight *bool) (*model.DividendConnection, error) {
+	return resolvers.DividendHistory(ctx, obj, from, to, first, after, last, before, singleFlight)
+}
+
+// NextEarningsDate delegates the SymbolDefinition.nextEarningsDate field resolution.
+func (r *symbolDefinitionResolver) NextEarningsDate(ctx context.Context, obj *model.SymbolDefinition, singleFlight *bool) (*time.Time, error) {
+	return resolvers.NextEarningsDate(ctx, obj, singleFlight)
+}
+
+// NextSplit delegates the SymbolDefinition.nextSplit field resolution.
+func (r *symbolDefinitionResolver) NextSplit(ctx context.Context, obj *model.SymbolDefinition, singleFlight *bool) (*model.Split, error) {
+	return resolvers.NextSplit(ctx, obj, singleFlight)
+}
+
+// Splits delegates the SymbolDefinition.splits field resolution.
+func (r *symbolDefinitionResolver) Splits(ctx context.Context, obj *model.SymbolDefinition, from *time.Time, to *time.Time, singleFlight *bool) ([]*model.Split, error) {
+	return resolvers.Splits(ctx, obj, from, to, singleFlight)
+}
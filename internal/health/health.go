@@ -0,0 +1,44 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Check reports the status of one dependency of the server.
+type Check struct {
+	// Name identifies the dependency in the response.
+	Name string
+	// Status returns whether the dependency is healthy and a short description of its state.
+	Status func() (healthy bool, detail string)
+}
+
+// componentStatus is the JSON form of a single check result.
+type componentStatus struct {
+	Healthy bool   `json:"healthy"`
+	Detail  string `json:"detail"`
+}
+
+// response is the JSON body served by the health endpoint.
+type response struct {
+	Status     string                     `json:"status"`
+	Components map[string]componentStatus `json:"components"`
+}
+
+// Handler serves the health endpoint. The server reports "ok" when every check is healthy
+// and "degraded" otherwise; it still answers 200 since it keeps serving requests.
+func Handler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := response{Status: "ok", Components: make(map[string]componentStatus, len(checks))}
+		for _, check := range checks {
+			healthy, detail := check.Status()
+			if !healthy {
+				resp.Status = "degraded"
+			}
+			resp.Components[check.Name] = componentStatus{Healthy: healthy, Detail: detail}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,321 @@
+package incremental
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/symbols"
+)
+
+// stream is a Query.symbols field marked with @stream.
+type stream struct {
+	field        *ast.Field
+	label        string
+	names        []any
+	initialCount int
+}
+
+// AroundOperations is a gqlgen operation middleware executing the Query.symbols fields marked
+// with @stream incrementally: the initial payload carries their first initialCount symbols,
+// and every other symbol follows in a payload of its own as soon as it has resolved.
+//
+// Each streamed symbol is executed as an operation of its own, started with the initial one
+// and sharing the request's loader, so the loader-backed fields of every symbol are still
+// fetched in the same batches. Operations not executed by MultipartMixed run unchanged.
+func AroundOperations(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	opCtx := graphql.GetOperationContext(ctx)
+	items := itemsFrom(ctx)
+	if items == nil || opCtx.Operation == nil || opCtx.Operation.Operation != ast.Query {
+		return next(ctx)
+	}
+	streams, err := findStreams(opCtx)
+	if err != nil {
+		return graphql.OneShot(&graphql.Response{Errors: gqlerror.List{err}})
+	}
+	if len(streams) == 0 {
+		return next(ctx)
+	}
+
+	// The initial operation only resolves the first initialCount symbols of every stream
+	initialSet := make(ast.SelectionSet, len(opCtx.Operation.SelectionSet))
+	copy(initialSet, opCtx.Operation.SelectionSet)
+	var producers []*producer
+	for _, s := range streams {
+		for i, sel := range initialSet {
+			if sel == s.field {
+				initialSet[i] = withNames(s.field, s.names[:s.initialCount])
+			}
+		}
+		for i := s.initialCount; i < len(s.names); i++ {
+			item := withOperation(opCtx, ast.SelectionSet{withNames(s.field, s.names[i:i+1])})
+			producers = append(producers, &producer{
+				handler: next(graphql.WithOperationContext(ctx, item)),
+				stream:  s,
+				index:   i,
+			})
+		}
+		log.Printf("Streaming %d of the %d symbols of %s after the initial payload", len(s.names)-s.initialCount, len(s.names), s.field.Alias)
+	}
+	// Create the initial handler last: gqlgen keeps the context of the last one for the response
+	initial := &producer{handler: next(graphql.WithOperationContext(ctx, withOperation(opCtx, initialSet)))}
+
+	d := &delivery{
+		items:    items,
+		pending:  len(producers) + 1,
+		payloads: make(chan *graphql.Response, len(producers)),
+		ready:    make(chan struct{}),
+	}
+	started := false
+	return func(ctx context.Context) *graphql.Response {
+		if !started {
+			started = true
+			// Start the streamed symbols with the initial ones, so they share the first batch
+			for _, p := range producers {
+				go p.run(ctx, d)
+			}
+			return d.initial(ctx, initial)
+		}
+		select {
+		case resp, ok := <-d.payloads:
+			if !ok {
+				return nil
+			}
+			return resp
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// findStreams returns the Query.symbols fields of an operation marked with @stream, unless
+// their names are invalid: the field fails as a whole then, like without @stream.
+func findStreams(opCtx *graphql.OperationContext) ([]*stream, *gqlerror.Error) {
+	var streams []*stream
+	for _, sel := range opCtx.Operation.SelectionSet {
+		field, ok := sel.(*ast.Field)
+		if !ok || field.Name != "symbols" {
+			continue
+		}
+		directive := field.Directives.ForName("stream")
+		if directive == nil {
+			continue
+		}
+		args := directive.ArgumentMap(opCtx.Variables)
+		if enabled, ok := args["if"].(bool); ok && !enabled {
+			continue
+		}
+		initialCount := 0
+		if value := args["initialCount"]; value != nil {
+			// Literals are int64, variables json.Number
+			count, err := graphql.UnmarshalInt(value)
+			if err != nil {
+				return nil, initialCountError(field, "initialCount must be an Int")
+			}
+			initialCount = count
+		}
+		if initialCount < 0 {
+			return nil, initialCountError(field, "initialCount must not be negative")
+		}
+		label, _ := args["label"].(string)
+
+		names, ok := field.ArgumentMap(opCtx.Variables)["names"].([]any)
+		if !ok || initialCount >= len(names) || !validNames(names) {
+			continue
+		}
+		streams = append(streams, &stream{field: field, label: label, names: names, initialCount: initialCount})
+	}
+	return streams, nil
+}
+
+// initialCountError is the error for an invalid initialCount argument of @stream.
+func initialCountError(field *ast.Field, message string) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message:    message,
+		Path:       ast.Path{ast.PathName(field.Alias)},
+		Extensions: map[string]any{"code": "BAD_USER_INPUT", "argument": "initialCount"},
+	}
+}
+
+// validNames reports whether every name of a names argument is a valid symbol.
+func validNames(names []any) bool {
+	raw := make([]string, len(names))
+	for i, name := range names {
+		s, ok := name.(string)
+		if !ok {
+			return false
+		}
+		raw[i] = s
+	}
+	_, errs := symbols.NormalizeAll(raw)
+	return len(errs) == 0
+}
+
+// withOperation returns a copy of an operation context selecting set at the root. The parsed
+// document is shared with other requests, so it is copied rather than changed.
+func withOperation(opCtx *graphql.OperationContext, set ast.SelectionSet) *graphql.OperationContext {
+	op := *opCtx.Operation
+	op.SelectionSet = set
+	copied := *opCtx
+	copied.Operation = &op
+	return &copied
+}
+
+// withNames returns a copy of a Query.symbols field with its names argument set to names.
+func withNames(field *ast.Field, names []any) *ast.Field {
+	list := &ast.Value{Kind: ast.ListValue, Position: field.Position}
+	for _, name := range names {
+		list.Children = append(list.Children, &ast.ChildValue{
+			Value: &ast.Value{Kind: ast.StringValue, Raw: name.(string), Position: field.Position},
+		})
+	}
+	copied := *field
+	copied.Arguments = ast.ArgumentList{{Name: "names", Value: list, Position: field.Position}}
+	return &copied
+}
+
+// delivery hands the payloads of the producers of an operation to the transport in the order
+// they are ready, the initial payload first.
+type delivery struct {
+	// items is where the transport finds the items of the @stream payloads
+	items    *streamedItems
+	mu       sync.Mutex
+	pending  int
+	payloads chan *graphql.Response
+	// ready is closed once the initial payload is out, holding back the others until then
+	ready     chan struct{}
+	cancelled bool
+}
+
+// initial returns the initial payload, and lets the other payloads follow it.
+func (d *delivery) initial(ctx context.Context, p *producer) *graphql.Response {
+	resp := p.handler(ctx)
+	defer close(d.ready)
+	if resp == nil || isNull(resp.Data) {
+		// No list to add items to: the streamed symbols are dropped
+		d.mu.Lock()
+		d.cancelled = true
+		d.mu.Unlock()
+		close(d.payloads)
+		return resp
+	}
+
+	d.mu.Lock()
+	if last(resp) {
+		d.pending--
+	}
+	hasNext := true
+	resp.HasNext = &hasNext
+	d.mu.Unlock()
+
+	if !last(resp) {
+		// Deferred fragments of the initial symbols
+		go p.run(ctx, d)
+	}
+	return resp
+}
+
+// send queues a payload, and closes the queue after the last payload of the last producer.
+func (d *delivery) send(ctx context.Context, resp *graphql.Response) {
+	<-d.ready
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cancelled {
+		return
+	}
+	if last(resp) {
+		d.pending--
+	}
+	hasNext := d.pending > 0
+	resp.HasNext = &hasNext
+	select {
+	case d.payloads <- resp:
+	case <-ctx.Done():
+		d.cancelled = true
+	}
+	if !hasNext || d.cancelled {
+		close(d.payloads)
+	}
+}
+
+// producer is one of the operations an operation with @stream is executed as.
+type producer struct {
+	handler graphql.ResponseHandler
+	// stream is the stream of the symbol the operation resolves, nil for the initial operation.
+	stream *stream
+	// index is the index of the symbol in its stream.
+	index int
+}
+
+// run sends the payloads of the operation, until its last one.
+func (p *producer) run(ctx context.Context, d *delivery) {
+	first := p.stream != nil
+	for {
+		resp := p.handler(ctx)
+		if resp == nil {
+			return
+		}
+		if p.stream != nil {
+			p.rebase(resp, first, d.items)
+			first = false
+		}
+		isLast := last(resp)
+		d.send(ctx, resp)
+		if isLast {
+			return
+		}
+	}
+}
+
+// rebase turns a payload of the operation of a streamed symbol into a payload of the operation
+// it was streamed for: the symbol itself first, as a @stream payload whose item is added to
+// items, then its deferred fragments. Paths are made to point at the symbol's index in the list
+// instead of the index in its own operation.
+func (p *producer) rebase(resp *graphql.Response, first bool, items *streamedItems) {
+	alias := p.stream.field.Alias
+	if first {
+		var data map[string]json.RawMessage
+		var list []json.RawMessage
+		item := json.RawMessage("null")
+		if json.Unmarshal(resp.Data, &data) == nil && json.Unmarshal(data[alias], &list) == nil && len(list) == 1 {
+			item = list[0]
+		}
+		// A @stream payload is on the path of the list, its item in items instead of data
+		resp.Data = nil
+		resp.Path = ast.Path{ast.PathName(alias)}
+		resp.Label = p.stream.label
+		items.add(resp, item)
+	}
+	resp.Path = p.rebasePath(resp.Path)
+	for _, err := range resp.Errors {
+		err.Path = p.rebasePath(err.Path)
+	}
+}
+
+// rebasePath replaces the list index of a path below the streamed field.
+func (p *producer) rebasePath(path ast.Path) ast.Path {
+	if len(path) < 2 || path[0] != ast.PathName(p.stream.field.Alias) {
+		return path
+	}
+	if _, ok := path[1].(ast.PathIndex); !ok {
+		return path
+	}
+	rebased := append(ast.Path{}, path...)
+	rebased[1] = ast.PathIndex(p.index)
+	return rebased
+}
+
+// last reports whether a payload is the last one of its operation.
+func last(resp *graphql.Response) bool {
+	return resp.HasNext == nil || !*resp.HasNext
+}
+
+// isNull reports whether response data is missing or null.
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
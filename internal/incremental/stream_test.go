@@ -0,0 +1,153 @@
+package incremental
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cachecontrol"
+	gen "github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/graph"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/singleflight"
+)
+
+// postMultipart executes a query over MultipartMixed and returns every part as JSON objects.
+func postMultipart(t *testing.T, query string) []map[string]json.RawMessage {
+	t.Helper()
+	srv := handler.New(gen.NewExecutableSchema(gen.Config{
+		Resolvers: graph.NewResolver(),
+		Directives: gen.DirectiveRoot{
+			Auth: func(ctx context.Context, _ any, next graphql.Resolver, _ []string) (any, error) {
+				return next(ctx)
+			},
+			CacheControl: cachecontrol.Directive,
+			SingleFlight: singleflight.Directive,
+		},
+	}))
+	srv.AddTransport(MultipartMixed{})
+	srv.AroundOperations(AroundOperations)
+
+	body, _ := json.Marshal(map[string]string{"query": query})
+	r := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "multipart/mixed")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, r)
+
+	_, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed with a boundary", w.Header().Get("Content-Type"))
+	}
+	reader := multipart.NewReader(w.Body, params["boundary"])
+	var parts []map[string]json.RawMessage
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("reading part %d: %v", len(parts), err)
+		}
+		var payload map[string]json.RawMessage
+		if err := json.NewDecoder(part).Decode(&payload); err != nil {
+			t.Fatalf("decoding part %d: %v", len(parts), err)
+		}
+		parts = append(parts, payload)
+	}
+}
+
+// incrementalPayloads returns the incremental payloads of the parts after the initial one.
+func incrementalPayloads(t *testing.T, parts []map[string]json.RawMessage) []map[string]any {
+	t.Helper()
+	var payloads []map[string]any
+	for i, part := range parts[1:] {
+		var incremental []map[string]any
+		if err := json.Unmarshal(part["incremental"], &incremental); err != nil || len(incremental) == 0 {
+			t.Fatalf("part %d = %v, want incremental payloads", i+1, part)
+		}
+		payloads = append(payloads, incremental...)
+	}
+	return payloads
+}
+
+// decode decodes a JSON literal for comparisons with decoded payloads.
+func decode(t *testing.T, literal string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(literal), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestStreamPayloads(t *testing.T) {
+	parts := postMultipart(t, `{ s: symbols(names: ["AAPL", "MSFT", "GOOG"]) @stream(initialCount: 1, label: "rest") { Name } }`)
+	if len(parts) != 3 {
+		t.Fatalf("got %d parts, want the initial one and one per streamed symbol", len(parts))
+	}
+
+	if got, want := decode(t, string(parts[0]["data"])), decode(t, `{"s": [{"Name": "AAPL"}]}`); !reflect.DeepEqual(got, want) {
+		t.Errorf("initial data = %v, want %v", got, want)
+	}
+
+	streamed := map[string]bool{}
+	for i, payload := range incrementalPayloads(t, parts) {
+		if _, ok := payload["data"]; ok {
+			t.Errorf("payload %d has data, want items: %v", i, payload)
+		}
+		items, ok := payload["items"].([]any)
+		if !ok || len(items) != 1 {
+			t.Fatalf("payload %d items = %v, want one item", i, payload["items"])
+		}
+		name, _ := items[0].(map[string]any)["Name"].(string)
+		streamed[name] = true
+		if got := payload["path"]; !reflect.DeepEqual(got, []any{"s"}) {
+			t.Errorf("payload %d path = %v, want the list path [s]", i, got)
+		}
+		if payload["label"] != "rest" {
+			t.Errorf("payload %d label = %v, want rest", i, payload["label"])
+		}
+	}
+	if !streamed["MSFT"] || !streamed["GOOG"] {
+		t.Errorf("streamed %v, want MSFT and GOOG", streamed)
+	}
+}
+
+func TestDeferredPayloadKeepsData(t *testing.T) {
+	items := &streamedItems{items: make(map[*graphql.Response]json.RawMessage)}
+	streamed := &graphql.Response{Data: json.RawMessage(`{"Name":"MSFT"}`), Path: ast.Path{ast.PathName("s")}, Label: "rest"}
+	items.add(streamed, json.RawMessage(`{"Name":"MSFT"}`))
+	deferred := &graphql.Response{Data: json.RawMessage(`{"Name":"AAPL"}`), Path: ast.Path{ast.PathName("s"), ast.PathIndex(0)}, Label: "late"}
+
+	tests := []struct {
+		name string
+		resp *graphql.Response
+		want string
+	}{
+		{name: "stream", resp: streamed, want: `{"items": [{"Name": "MSFT"}], "label": "rest", "path": ["s"]}`},
+		{name: "defer", resp: deferred, want: `{"data": {"Name": "AAPL"}, "label": "late", "path": ["s", 0]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(items.payload(tt.resp))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := decode(t, string(b)), decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("payload = %s, want %s", b, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package incremental delivers parts of query responses after the initial payload, over the
+// multipart/mixed transport: fragments marked with @defer, which the generated code executes
+// itself, and the items of Query.symbols lists marked with @stream.
+package incremental
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+type contextKey struct{}
+
+// MultipartMixed is a multipart/mixed transport, marking the operations it executes as able
+// to receive payloads after the first one. Other transports only read the first payload, so
+// lists marked with @stream are returned whole to them.
+//
+// It writes payloads like gqlgen's transport.MultipartMixed, one part per payload, except for
+// @stream payloads: they carry their item in items, on the path of the list, as the
+// incremental delivery spec has it, where @defer payloads carry data on the path of the object.
+type MultipartMixed struct {
+	// Boundary separates the parts. Empty uses "-".
+	Boundary string
+}
+
+var _ graphql.Transport = MultipartMixed{}
+
+// Supports implements graphql.Transport: POSTs of JSON accepting multipart/mixed.
+func (t MultipartMixed) Supports(r *http.Request) bool {
+	return transport.MultipartMixed{}.Supports(r)
+}
+
+// Do implements graphql.Transport.
+func (t MultipartMixed) Do(w http.ResponseWriter, r *http.Request, exec graphql.GraphExecutor) {
+	items := &streamedItems{items: make(map[*graphql.Response]json.RawMessage)}
+	ctx := context.WithValue(r.Context(), contextKey{}, items)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		transport.SendErrorf(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Replaced once the operation is valid, errors before that are plain JSON
+	w.Header().Set("Content-Type", "application/json")
+
+	start := graphql.Now()
+	params := &graphql.RawParams{}
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(params); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, exec.DispatchError(ctx, gqlerror.List{gqlerror.Errorf("json request body could not be decoded: %v", err)}))
+		return
+	}
+	params.Headers = r.Header
+	params.ReadTime = graphql.TraceTiming{Start: start, End: graphql.Now()}
+
+	rc, errs := exec.CreateOperationContext(ctx, params)
+	ctx = graphql.WithOperationContext(ctx, rc)
+	if errs != nil {
+		status := http.StatusOK
+		if errcode.GetErrorKind(errs) == errcode.KindProtocol {
+			status = http.StatusUnprocessableEntity
+		}
+		w.WriteHeader(status)
+		writeJSON(w, exec.DispatchError(ctx, errs))
+		return
+	}
+
+	boundary := t.Boundary
+	if boundary == "" {
+		boundary = "-"
+	}
+	w.Header().Set("Content-Type", fmt.Sprintf(`multipart/mixed;boundary="%s";deferSpec=20220824`, boundary))
+
+	responses, ctx := exec.DispatchOperation(ctx, rc)
+	fmt.Fprintf(w, "--%s\r\n", boundary)
+	initial := true
+	for {
+		resp := responses(ctx)
+		if resp == nil {
+			break
+		}
+		hasNext := resp.HasNext != nil && *resp.HasNext
+
+		fmt.Fprint(w, "Content-Type: application/json\r\n\r\n")
+		if initial {
+			writeJSON(w, resp)
+			initial = false
+		} else {
+			writeJSON(w, struct {
+				Incremental []any `json:"incremental"`
+				HasNext     bool  `json:"hasNext"`
+			}{[]any{items.payload(resp)}, hasNext})
+		}
+		if !hasNext {
+			fmt.Fprintf(w, "\r\n--%s--\r\n", boundary)
+			flusher.Flush()
+			return
+		}
+		fmt.Fprintf(w, "\r\n--%s\r\n", boundary)
+		flusher.Flush()
+	}
+	// The operation ended without a last payload
+	fmt.Fprint(w, "Content-Type: application/json\r\n\r\n{\"hasNext\":false}\r\n")
+	fmt.Fprintf(w, "--%s--\r\n", boundary)
+	flusher.Flush()
+}
+
+// writeJSON writes v as JSON.
+func writeJSON(w io.Writer, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Unable to encode incremental payload: %v", err)
+		b, _ = json.Marshal(&graphql.Response{Errors: gqlerror.List{gqlerror.Errorf("unable to encode result")}})
+	}
+	_, _ = w.Write(b)
+}
+
+// streamedItems remembers the items of the @stream payloads of an operation executed by
+// MultipartMixed, until the transport writes them.
+type streamedItems struct {
+	mu    sync.Mutex
+	items map[*graphql.Response]json.RawMessage
+}
+
+// add marks resp as the payload of a @stream item.
+func (s *streamedItems) add(resp *graphql.Response, item json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[resp] = item
+}
+
+// payload returns the incremental payload resp is written as.
+func (s *streamedItems) payload(resp *graphql.Response) any {
+	s.mu.Lock()
+	item, ok := s.items[resp]
+	delete(s.items, resp)
+	s.mu.Unlock()
+	if !ok {
+		return resp
+	}
+	return streamPayload{
+		Errors:     resp.Errors,
+		Items:      []json.RawMessage{item},
+		Label:      resp.Label,
+		Path:       resp.Path,
+		HasNext:    resp.HasNext,
+		Extensions: resp.Extensions,
+	}
+}
+
+// streamPayload is an incremental payload delivering items of a list marked with @stream.
+type streamPayload struct {
+	Errors     gqlerror.List     `json:"errors,omitempty"`
+	Items      []json.RawMessage `json:"items"`
+	Label      string            `json:"label,omitempty"`
+	Path       ast.Path          `json:"path"`
+	HasNext    *bool             `json:"hasNext,omitempty"`
+	Extensions map[string]any    `json:"extensions,omitempty"`
+}
+
+// itemsFrom returns where the items of the @stream payloads of the operation are kept, or
+// nil when it isn't executed by MultipartMixed.
+func itemsFrom(ctx context.Context) *streamedItems {
+	items, _ := ctx.Value(contextKey{}).(*streamedItems)
+	return items
+}
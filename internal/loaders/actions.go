@@ -0,0 +1,171 @@
+package loaders
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
+)
+
+// actionsSource serves nextDividend, nextEarningsDate, nextSplit and splits through one
+// combined upstream call when set. Nil keeps one loader and one upstream call per field.
+var actionsSource upstream.CorporateActionsSource
+
+// SetCorporateActionsSource switches the corporate action fields to multi-field loader mode:
+// they share one dataloader, so their keys are collected in one batch window and fetched in
+// one call to src. Nil switches back to one loader per field.
+// It should be called once at startup, before the server accepts requests.
+func SetCorporateActionsSource(src upstream.CorporateActionsSource) {
+	actionsSource = src
+}
+
+// actionKey is the loader key of one field of one symbol in multi-field loader mode.
+type actionKey struct {
+	Symbol string
+	Field  upstream.ActionField
+}
+
+// corporateActions is the batch shared by the corporate action fields in multi-field loader
+// mode. Results are split back per field and cached in each field's own namespace, so both
+// modes read and prime the same shared cache entries.
+var corporateActions = &batch[actionKey, any]{
+	name:   "corporate actions",
+	fetch:  fetchCorporateActions,
+	encode: func(k actionKey) string { return string(k.Field) + "|" + k.Symbol },
+	cache: actionStore{
+		upstream.ActionNextDividend:     anyStore[*upstream.Dividend]{nextDividends.cache},
+		upstream.ActionNextEarningsDate: anyStore[*time.Time]{nextEarningsDates.cache},
+		upstream.ActionNextSplit:        anyStore[*upstream.Split]{nextSplits.cache},
+		upstream.ActionSplits:           anyStore[[]upstream.Split]{splitHistories.cache},
+	},
+	absent: func(v any) bool { return v == nil },
+}
+
+// fetchCorporateActions makes one upstream call for every symbol in the batch, asking for
+// every field any of its keys needs, and splits the response back per key.
+func fetchCorporateActions(ctx context.Context, keys []actionKey) ([]any, []error) {
+	var symbols []string
+	var fields []upstream.ActionField
+	symbolIdx := make(map[string]int)
+	seenFields := make(map[upstream.ActionField]bool)
+	for _, key := range keys {
+		if _, seen := symbolIdx[key.Symbol]; !seen {
+			symbolIdx[key.Symbol] = len(symbols)
+			symbols = append(symbols, key.Symbol)
+		}
+		if !seenFields[key.Field] {
+			seenFields[key.Field] = true
+			fields = append(fields, key.Field)
+		}
+	}
+
+	actions, errs := actionsSource.FetchCorporateActions(ctx, symbols, fields)
+	if len(actions) != len(symbols) || len(errs) != len(symbols) {
+		err := fmt.Errorf("upstream returned %d results and %d errors for %d symbols", len(actions), len(errs), len(symbols))
+		return make([]any, len(keys)), errorsFor(len(keys), err)
+	}
+
+	results := make([]any, len(keys))
+	keyErrs := make([]error, len(keys))
+	for i, key := range keys {
+		j := symbolIdx[key.Symbol]
+		if errs[j] != nil {
+			keyErrs[i] = errs[j]
+			continue
+		}
+		results[i] = actionValue(actions[j], key.Field)
+	}
+	return results, keyErrs
+}
+
+// actionValue picks one field out of a combined response. Missing values are returned as
+// an untyped nil, so they are recognised as absent and not cached.
+func actionValue(actions *upstream.CorporateActions, field upstream.ActionField) any {
+	if actions == nil {
+		return nil
+	}
+	switch field {
+	case upstream.ActionNextDividend:
+		if actions.NextDividend != nil {
+			return actions.NextDividend
+		}
+	case upstream.ActionNextEarningsDate:
+		if actions.NextEarningsDate != nil {
+			return actions.NextEarningsDate
+		}
+	case upstream.ActionNextSplit:
+		if actions.NextSplit != nil {
+			return actions.NextSplit
+		}
+	case upstream.ActionSplits:
+		// No splits is an answer worth caching, unlike a missing next split
+		return append([]upstream.Split{}, actions.Splits...)
+	}
+	return nil
+}
+
+// loadAction loads one field through the shared multi-field loader.
+func loadAction[V any](ctx context.Context, l *fieldLoader[actionKey, any], field upstream.ActionField, symbolName string) (V, error) {
+	v, err := l.load(ctx, actionKey{Symbol: symbolName, Field: field})
+	typed, _ := v.(V)
+	return typed, err
+}
+
+// actionStore routes the cache keys of the multi-field loader to each field's namespace.
+type actionStore map[upstream.ActionField]store[any]
+
+// route splits a multi-field cache key into the field's store and its own key.
+func (s actionStore) route(key string) (store[any], string) {
+	field, symbol, _ := strings.Cut(key, "|")
+	return s[upstream.ActionField(field)], symbol
+}
+
+// Get implements store.
+func (s actionStore) Get(key string) (any, bool) {
+	fieldStore, symbol := s.route(key)
+	return fieldStore.Get(symbol)
+}
+
+// GetStale implements store.
+func (s actionStore) GetStale(key string) (any, bool) {
+	fieldStore, symbol := s.route(key)
+	return fieldStore.GetStale(symbol)
+}
+
+// Set implements store.
+func (s actionStore) Set(key string, value any) {
+	fieldStore, symbol := s.route(key)
+	fieldStore.Set(symbol, value)
+}
+
+// anyStore adapts a typed store to hold values of any type.
+type anyStore[V any] struct {
+	typed store[V]
+}
+
+// Get implements store.
+func (s anyStore[V]) Get(key string) (any, bool) {
+	v, found := s.typed.Get(key)
+	if !found {
+		return nil, false
+	}
+	return v, true
+}
+
+// GetStale implements store.
+func (s anyStore[V]) GetStale(key string) (any, bool) {
+	v, found := s.typed.GetStale(key)
+	if !found {
+		return nil, false
+	}
+	return v, true
+}
+
+// Set implements store.
+func (s anyStore[V]) Set(key string, value any) {
+	if typed, ok := value.(V); ok {
+		s.typed.Set(key, typed)
+	}
+}
@@ -0,0 +1,262 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/breaker"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
+	"github.com/vikstrous/dataloadgen"
+)
+
+// batch describes one kind of loader-backed data: how it is fetched from the upstream
+// and where it is kept in the shared cache. Keys may be plain symbols or composite structs
+// carrying field arguments.
+type batch[K comparable, V any] struct {
+	// name identifies the data in logs.
+	name string
+	// fetch makes a single upstream call for a set of keys.
+	fetch func(ctx context.Context, keys []K) ([]V, []error)
+	// encode returns the canonical form of a key, used as the shared cache key.
+	encode func(K) string
+	// group returns the canonical form of the parameters a key shares with others in one
+	// upstream call. Keys are fetched with one call per group. Nil puts every key in one call.
+	group func(K) string
+	// cache is where the results are stored in the shared cache, usually a cache.Namespace.
+	cache store[V]
+	// absent reports values meaning "no data", which are not cached. Nil caches every value.
+	absent func(V) bool
+	// override returns a value set by an operator for an encoded key, which takes precedence
+	// over the shared cache and the upstream. Nil disables overrides.
+	override func(key string) (V, bool)
+	// call makes one logical upstream call that may issue several physical ones, such as a
+	// hedged call, each going through callUpstream. Nil calls callUpstream with fetch once.
+	call func(ctx context.Context, keys []K) ([]V, []error)
+}
+
+// store is the part of the shared cache a batch reads and primes.
+type store[V any] interface {
+	Get(key string) (V, bool)
+	GetStale(key string) (V, bool)
+	Set(key string, value V)
+}
+
+// symbolKey is the encoding of plain symbol keys.
+func symbolKey(symbol string) string {
+	return symbol
+}
+
+// load is the batch function used by dataloadgen. It checks the shared cache before
+// calling the upstream for the missing keys, retrying the keys that failed.
+func (b *batch[K, V]) load(ctx context.Context, keys []K) ([]V, []error) {
+	encoded := make([]string, len(keys))
+	for i, key := range keys {
+		encoded[i] = b.encode(key)
+	}
+	log.Printf("DataLoader Batch Function called for %s keys: %v", b.name, encoded)
+	ctx, cancel := upstreamContext(ctx)
+	defer cancel()
+
+	results := make([]V, len(keys))
+	errs := make([]error, len(keys))
+
+	// --- Check Overrides and the Shared Cache First ---
+	// Missing keys are grouped by shared parameters, with the original results index of each
+	groups := make(map[string][]int)
+	var groupOrder []string
+
+	for i, key := range keys {
+		if b.override != nil {
+			if overridden, found := b.override(encoded[i]); found {
+				log.Printf("Override HIT for %s key: %s", b.name, encoded[i])
+				results[i] = overridden
+				continue
+			}
+		}
+		if cachedVal, found := b.cache.Get(encoded[i]); found {
+			log.Printf("Shared cache HIT for %s key: %s", b.name, encoded[i])
+			results[i] = cachedVal
+			continue
+		}
+		log.Printf("Shared cache MISS for %s key: %s", b.name, encoded[i])
+		group := ""
+		if b.group != nil {
+			group = b.group(key)
+		}
+		if _, seen := groups[group]; !seen {
+			groupOrder = append(groupOrder, group)
+		}
+		groups[group] = append(groups[group], i)
+	}
+
+	// --- Fetch Missing Keys from the Upstream API, one call per parameter set ---
+	var wg sync.WaitGroup
+	for _, group := range groupOrder {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			b.fetchMissing(ctx, keys, encoded, indexes, results, errs)
+		}(groups[group])
+	}
+	wg.Wait()
+
+	log.Printf("DataLoader Batch Function finished for %s keys: %v", b.name, encoded)
+	return results, errs
+}
+
+// fetchMissing fetches the keys at indexes, which share their parameters, and stores their
+// results and errors at the same indexes. Successful results are added to the shared cache.
+func (b *batch[K, V]) fetchMissing(ctx context.Context, keys []K, encoded []string, indexes []int, results []V, errs []error) {
+	keysToFetchFromApi := make([]K, len(indexes))
+	for apiIdx, origIdx := range indexes {
+		keysToFetchFromApi[apiIdx] = keys[origIdx]
+	}
+
+	// Only the keys that failed are retried, cache hits are never refetched
+	apiResults, apiErrors := upstream.Retry(ctx, retryPolicy, keysToFetchFromApi, func(ctx context.Context, keys []K) ([]V, []error) {
+		if b.call != nil {
+			return b.call(ctx, keys)
+		}
+		return callUpstream(ctx, keys, b.fetch)
+	})
+
+	// --- Populate main results slice from API results ---
+	for apiIdx, origIdx := range indexes {
+		key := encoded[origIdx]
+		if apiErrors[apiIdx] != nil {
+			if stale, found := b.staleFallback(key, apiErrors[apiIdx]); found {
+				results[origIdx] = stale
+				continue
+			}
+			log.Printf("API error for %s %s: %v", b.name, key, apiErrors[apiIdx])
+			errs[origIdx] = apiErrors[apiIdx]
+			continue
+		}
+		results[origIdx] = apiResults[apiIdx]
+		if b.absent == nil || !b.absent(apiResults[apiIdx]) {
+			// Add successful results to the shared cache
+			log.Printf("Adding API result for %s %s to shared cache", b.name, key)
+			b.cache.Set(key, apiResults[apiIdx])
+		}
+	}
+}
+
+// staleFallback returns the last known value for a key whose fetch was rejected by the
+// open circuit breaker, if the shared cache still has one.
+func (b *batch[K, V]) staleFallback(key string, err error) (V, bool) {
+	var openErr *breaker.OpenError
+	if !errors.As(err, &openErr) {
+		var zero V
+		return zero, false
+	}
+	stale, found := b.cache.GetStale(key)
+	if found {
+		log.Printf("Circuit breaker open, serving stale shared cache value for %s %s", b.name, key)
+		staleServed.Inc()
+	}
+	return stale, found
+}
+
+// callUpstream makes a single upstream call. Every call, including retries, is charged
+// against the upstream quota and recorded by the circuit breaker.
+func callUpstream[K, V any](ctx context.Context, keys []K, fetch func(context.Context, []K) ([]V, []error)) ([]V, []error) {
+	// Fail fast instead of waiting on an upstream that is known to be down. Checked before
+	// the quota, so rejected calls don't queue for or spend an upstream token.
+	var token breaker.Token
+	if upstreamBreaker != nil {
+		var err error
+		if token, err = upstreamBreaker.Allow(); err != nil {
+			breakerRejections.Inc()
+			return make([]V, len(keys)), errorsFor(len(keys), err)
+		}
+	}
+
+	if upstreamQuota != nil {
+		// Queue for (or be rejected by) the upstream budget before calling the API
+		if err := upstreamQuota.Acquire(ctx); err != nil {
+			if upstreamBreaker != nil {
+				// The call is never made, so it must not hold on to a half-open probe slot
+				upstreamBreaker.Release(token)
+			}
+			return make([]V, len(keys)), errorsFor(len(keys), err)
+		}
+	}
+
+	results, errs := fetch(ctx, keys)
+
+	// Never index past a response of the wrong length, fail the whole call instead
+	if err := upstream.CheckResponse(len(results), len(errs), len(keys)); err != nil {
+		log.Printf("Discarding upstream response for %d keys: %v", len(keys), err)
+		results, errs = make([]V, len(keys)), errorsFor(len(keys), err)
+	}
+
+	if upstreamBreaker != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			// Cancelled by the caller, e.g. a hedge that lost the race: says nothing about the upstream
+			upstreamBreaker.Release(token)
+		} else {
+			upstreamBreaker.Record(token, !upstreamFailed(errs))
+		}
+	}
+	return results, errs
+}
+
+// fieldLoader is the request-scoped dataloader behind one loader-backed field.
+type fieldLoader[K comparable, V any] struct {
+	// field names the field in logs
+	field  string
+	encode func(K) string
+	loader *dataloadgen.Loader[K, V]
+}
+
+// newFieldLoader creates a dataloader for b.
+func newFieldLoader[K comparable, V any](field string, b *batch[K, V]) *fieldLoader[K, V] {
+	return &fieldLoader[K, V]{
+		field:  field,
+		encode: b.encode,
+		loader: dataloadgen.NewLoader(b.load, dataloadgen.WithWait(batchWait)),
+	}
+}
+
+// load loads the value for a key. The dataloader's request-scoped cache answers repeats;
+// a miss triggers the batch function, which checks the shared cache.
+func (l *fieldLoader[K, V]) load(ctx context.Context, key K) (V, error) {
+	if fieldTimeout <= 0 {
+		return l.loader.Load(ctx, key)
+	}
+	return l.loadWithTimeout(ctx, key, l.encode(key))
+}
+
+// loadWithTimeout waits for the dataloader up to the field timeout. The batch keeps running
+// after a timeout, so its result still reaches the shared cache for subsequent requests.
+func (l *fieldLoader[K, V]) loadWithTimeout(ctx context.Context, key K, symbolName string) (V, error) {
+	type loadResult struct {
+		value V
+		err   error
+	}
+	var zero V
+
+	// LoadThunk queues the key immediately; the thunk blocks until the batch is done
+	thunk := l.loader.LoadThunk(ctx, key)
+	done := make(chan loadResult, 1)
+	go func() {
+		value, err := thunk()
+		done <- loadResult{value, err}
+	}()
+
+	timer := time.NewTimer(fieldTimeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-timer.C:
+		log.Printf("Symbol %s timed out for %s after %s, resolving to nil", symbolName, l.field, fieldTimeout)
+		return zero, &FieldTimeoutError{Key: symbolName, Timeout: fieldTimeout}
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
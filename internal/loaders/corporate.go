@@ -0,0 +1,84 @@
+package loaders
+
+import (
+	"context"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cache"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
+)
+
+// earningsSource is the upstream API the earnings batch function fetches from.
+var earningsSource upstream.EarningsSource = upstream.NewSimulatedSource()
+
+// splitSource is the upstream API the split batch functions fetch from.
+var splitSource upstream.SplitSource = upstream.NewSimulatedSource()
+
+// SetEarningsSource replaces the upstream source used for earnings dates.
+// It should be called once at startup, before the server accepts requests.
+func SetEarningsSource(src upstream.EarningsSource) {
+	earningsSource = src
+}
+
+// SetSplitSource replaces the upstream source used for stock splits.
+// It should be called once at startup, before the server accepts requests.
+func SetSplitSource(src upstream.SplitSource) {
+	splitSource = src
+}
+
+// nextEarningsDates fetches the next scheduled earnings date of each symbol.
+var nextEarningsDates = &batch[string, *time.Time]{
+	name: "next earnings date",
+	fetch: func(ctx context.Context, keys []string) ([]*time.Time, []error) {
+		return earningsSource.FetchNextEarningsDates(ctx, keys)
+	},
+	encode: symbolKey,
+	cache:  cache.NewNamespace[*time.Time]("nextEarningsDate"),
+	absent: func(date *time.Time) bool { return date == nil },
+}
+
+// nextSplits fetches the next announced split of each symbol.
+var nextSplits = &batch[string, *upstream.Split]{
+	name: "next split",
+	fetch: func(ctx context.Context, keys []string) ([]*upstream.Split, []error) {
+		return splitSource.FetchNextSplits(ctx, keys)
+	},
+	encode: symbolKey,
+	cache:  cache.NewNamespace[*upstream.Split]("nextSplit"),
+	absent: func(split *upstream.Split) bool { return split == nil },
+}
+
+// splitHistories fetches the whole split history of each symbol. Splits are rare, so
+// date ranges are applied by the resolver and every range is served by one upstream call.
+var splitHistories = &batch[string, []upstream.Split]{
+	name: "split history",
+	fetch: func(ctx context.Context, keys []string) ([][]upstream.Split, []error) {
+		return splitSource.FetchSplits(ctx, keys)
+	},
+	encode: symbolKey,
+	cache:  cache.NewNamespace[[]upstream.Split]("splits"),
+}
+
+// LoadNextEarningsDate loads the next earnings date for a symbol.
+func (d *DividendDateLoader) LoadNextEarningsDate(ctx context.Context, symbolName string) (*time.Time, error) {
+	if d.actions != nil {
+		return loadAction[*time.Time](ctx, d.actions, upstream.ActionNextEarningsDate, symbolName)
+	}
+	return d.earningsDates.load(ctx, symbolName)
+}
+
+// LoadNextSplit loads the next announced split for a symbol.
+func (d *DividendDateLoader) LoadNextSplit(ctx context.Context, symbolName string) (*upstream.Split, error) {
+	if d.actions != nil {
+		return loadAction[*upstream.Split](ctx, d.actions, upstream.ActionNextSplit, symbolName)
+	}
+	return d.nextSplits.load(ctx, symbolName)
+}
+
+// LoadSplits loads the split history for a symbol, oldest first.
+func (d *DividendDateLoader) LoadSplits(ctx context.Context, symbolName string) ([]upstream.Split, error) {
+	if d.actions != nil {
+		return loadAction[[]upstream.Split](ctx, d.actions, upstream.ActionSplits, symbolName)
+	}
+	return d.splits.load(ctx, symbolName)
+}
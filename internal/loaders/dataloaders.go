@@ -2,171 +2,223 @@ package loaders
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/breaker"
 	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cache"
-	"github.com/vikstrous/dataloadgen"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/metrics"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/ratelimit"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
 )
 
-// DividendDateLoader is a DataLoader for fetching dividend dates by symbol name
+// DividendDateLoader holds the request-scoped DataLoaders for the loader-backed fields of a symbol
 type DividendDateLoader struct {
-	loader *dataloadgen.Loader[string, *time.Time]
-	// Track which symbols have already been attempted in this request/subscription cycle
-	attemptTracker *SymbolAttemptTracker
+	dates         *fieldLoader[string, *time.Time]
+	nextDividends *fieldLoader[string, *upstream.Dividend]
+	histories     *fieldLoader[historyKey, *upstream.HistoryPage]
+	earningsDates *fieldLoader[string, *time.Time]
+	nextSplits    *fieldLoader[string, *upstream.Split]
+	splits        *fieldLoader[string, []upstream.Split]
+	// actions replaces nextDividends, earningsDates, nextSplits and splits in multi-field loader mode
+	actions *fieldLoader[actionKey, any]
+}
+
+// NewDividendDateLoader creates a new DividendDateLoader
+func NewDividendDateLoader() *DividendDateLoader {
+	d := &DividendDateLoader{
+		dates:         newFieldLoader("NextExDividendDate", dividendDates),
+		nextDividends: newFieldLoader("nextDividend", nextDividends),
+		histories:     newFieldLoader("dividendHistory", dividendHistories),
+		earningsDates: newFieldLoader("nextEarningsDate", nextEarningsDates),
+		nextSplits:    newFieldLoader("nextSplit", nextSplits),
+		splits:        newFieldLoader("splits", splitHistories),
+	}
+	if actionsSource != nil {
+		d.actions = newFieldLoader("corporateActions", corporateActions)
+	}
+	return d
 }
 
-// SymbolAttemptTracker tracks which symbol names have been attempted in this request
-type SymbolAttemptTracker struct {
-	attemptedSymbols map[string]bool
+// LoadDividendDate loads the dividend date for a symbol
+func (d *DividendDateLoader) LoadDividendDate(ctx context.Context, symbolName string) (*time.Time, error) {
+	return d.dates.load(ctx, symbolName)
 }
 
-// NewSymbolAttemptTracker creates a new tracker
-func NewSymbolAttemptTracker() *SymbolAttemptTracker {
-	return &SymbolAttemptTracker{
-		attemptedSymbols: make(map[string]bool),
+// DividendDateTTL returns how long the dividend date served for a symbol stays valid: until
+// its override expires, or until it expires from the shared cache. The second result is false
+// for overrides that never expire. Dates that aren't cached, like missing ones, are valid for 0.
+func DividendDateTTL(symbolName string) (time.Duration, bool) {
+	if o, found := activeDividendDateOverride(symbolName); found {
+		if o.ExpiresAt == nil {
+			return 0, false
+		}
+		return time.Until(*o.ExpiresAt), true
 	}
+	ttl, _ := cache.DividendDates.TTL(symbolName)
+	return ttl, true
 }
 
-// IsAttempted checks if a symbol has been attempted
-func (t *SymbolAttemptTracker) IsAttempted(symbol string) bool {
-	return t.attemptedSymbols[symbol]
+// FieldTimeoutError is returned when a loader-backed field doesn't resolve within the field timeout.
+type FieldTimeoutError struct {
+	// Key is the loader key that timed out.
+	Key string
+	// Timeout is the field timeout that was exceeded.
+	Timeout time.Duration
 }
 
-// MarkAttempted marks a symbol as attempted
-func (t *SymbolAttemptTracker) MarkAttempted(symbol string) {
-	t.attemptedSymbols[symbol] = true
+// Error implements error.
+func (e *FieldTimeoutError) Error() string {
+	return fmt.Sprintf("upstream did not respond for %s within %s", e.Key, e.Timeout)
 }
 
-// NewDividendDateLoader creates a new DividendDateLoader
-func NewDividendDateLoader() *DividendDateLoader {
-	return &DividendDateLoader{
-		loader:         dataloadgen.NewLoader(fetchDividendDates),
-		attemptTracker: NewSymbolAttemptTracker(),
-	}
+// dividendSource is the upstream API the batch function fetches from.
+var dividendSource upstream.DividendDateSource = upstream.NewAlignedSource(upstream.NewSimulatedSource())
+
+// dividendHedger hedges the dividend date upstream calls. Nil disables hedging.
+var dividendHedger *upstream.HedgedSource
+
+// retryPolicy controls how failed upstream keys are retried.
+var retryPolicy = upstream.DefaultRetryPolicy()
+
+// upstreamQuota is the global budget for calls to the dividend date upstream,
+// shared by every request. Nil disables the quota.
+var upstreamQuota *ratelimit.Quota
+
+// upstreamBreaker guards calls to the dividend date upstream. Nil disables it.
+var upstreamBreaker *breaker.Breaker
+
+// fieldTimeout bounds how long a loader-backed field waits for its batch. Zero disables it.
+var fieldTimeout time.Duration
+
+// batchWait is how long a dataloader collects keys before calling its batch function.
+var batchWait = 16 * time.Millisecond
+
+// upstreamTimeout bounds how long a batch may wait on the upstream, including retries.
+var upstreamTimeout = 10 * time.Second
+
+var (
+	breakerRejections = metrics.NewCounter("upstream_circuit_breaker_rejections_total",
+		"Upstream calls rejected because the circuit breaker was open.")
+	staleServed = metrics.NewCounter("dividend_date_stale_served_total",
+		"Stale dividend dates served from the shared cache while the circuit breaker was open.")
+)
+
+// SetDividendDateSource replaces the upstream source used by the batch function.
+// It should be called once at startup, before the server accepts requests.
+func SetDividendDateSource(src upstream.DividendDateSource) {
+	dividendSource = src
 }
 
-// LoadDividendDate loads the dividend date for a symbol, handling singleFlight logic
-func (d *DividendDateLoader) LoadDividendDate(ctx context.Context, symbolName string, singleFlight bool) (*time.Time, error) {
-	// Check if the symbol has already been attempted *in this request scope*
-	alreadyAttempted := d.attemptTracker.IsAttempted(symbolName)
+// SetHedging makes the dividend date batch hedge its upstream calls. The original call and
+// its hedge are charged to the upstream quota and recorded by the circuit breaker separately.
+// It should be called once at startup, before the server accepts requests.
+func SetHedging(cfg upstream.HedgeConfig) {
+	dividendHedger = upstream.NewHedgedSource(guardedDividendSource{}, cfg)
+}
 
-	// Early exit ONLY if singleFlight=true AND it was already attempted.
-	if singleFlight && alreadyAttempted {
-		log.Printf("Symbol %s already attempted in this scope with singleFlight=true, returning nil", symbolName)
-		return nil, nil
-	}
+// guardedDividendSource makes every call to the dividend date source through callUpstream.
+type guardedDividendSource struct{}
 
-	// Mark as attempted on the first encounter within this request scope.
-	// This ensures subsequent singleFlight=true calls for the same key return nil.
-	if !alreadyAttempted {
-		d.attemptTracker.MarkAttempted(symbolName)
-		log.Printf("Symbol %s first attempt in this scope (singleFlight=%t), marked. Proceeding to dataloader.", symbolName, singleFlight)
-	} else {
-		// Log if it was already attempted but singleFlight is false (will proceed to dataloader)
-		log.Printf("Symbol %s already attempted in this scope, but singleFlight=false. Proceeding to dataloader.", symbolName)
-	}
+// FetchDividendDates implements upstream.DividendDateSource.
+func (guardedDividendSource) FetchDividendDates(ctx context.Context, symbols []string) ([]*time.Time, []error) {
+	return callUpstream(ctx, symbols, dividendSource.FetchDividendDates)
+}
 
-	// Proceed to the dataloader.
-	// - If first attempt: dataloader might miss, triggering batch function (which checks shared cache).
-	// - If already attempted & singleFlight=false: dataloader should hit its internal request-scoped cache.
-	return d.loader.Load(ctx, symbolName)
-}
-
-// LoadManyDividendDates loads multiple dividend dates at once
-// Note: This simplistic LoadMany doesn't elegantly handle the singleFlight=false logic across multiple calls.
-// A more robust implementation might require modifying dataloadgen or a custom batch function.
-// For now, it assumes singleFlight=true behavior for simplicity when calling LoadMany.
-func (d *DividendDateLoader) LoadManyDividendDates(ctx context.Context, symbolNames []string) ([]*time.Time, []error) {
-	// Load each one individually using the main Load method (which respects singleFlight=true implicitly)
-	results := make([]*time.Time, len(symbolNames))
-	errors := make([]error, len(symbolNames))
-
-	log.Printf("LoadManyDividendDates called for %d symbols. Assuming singleFlight=true behavior.", len(symbolNames))
-	for i, name := range symbolNames {
-		// Assuming singleFlight=true for LoadMany for simplicity
-		results[i], errors[i] = d.loader.Load(ctx, name)
-	}
+// SetRetryPolicy replaces the retry policy used by the batch function.
+// It should be called once at startup, before the server accepts requests.
+func SetRetryPolicy(p upstream.RetryPolicy) {
+	retryPolicy = p
+}
 
-	return results, errors
-}
-
-// fetchDividendDates is the batch function used by dataloadgen.
-// It now checks a shared cache before simulating the API call.
-func fetchDividendDates(ctx context.Context, symbolNames []string) ([]*time.Time, []error) {
-	log.Printf("DataLoader Batch Function called for keys: %v", symbolNames)
-	results := make([]*time.Time, len(symbolNames))
-	errors := make([]error, len(symbolNames)) // Initialize error slice
-
-	// --- Check Shared Cache First ---
-	keysToFetchFromApi := make([]string, 0, len(symbolNames))
-	// Map API fetch index back to original results index
-	apiFetchIndexToOrigIndex := make(map[int]int, len(symbolNames))
-
-	for i, name := range symbolNames {
-		if cachedVal, found := cache.Get(name); found {
-			log.Printf("Shared cache HIT for key: %s", name)
-			results[i] = cachedVal
-		} else {
-			log.Printf("Shared cache MISS for key: %s", name)
-			apiFetchIndexToOrigIndex[len(keysToFetchFromApi)] = i
-			keysToFetchFromApi = append(keysToFetchFromApi, name)
-		}
+// SetUpstreamQuota installs the upstream quota used by the batch function.
+// It should be called once at startup, before the server accepts requests.
+func SetUpstreamQuota(q *ratelimit.Quota) {
+	upstreamQuota = q
+}
+
+// SetCircuitBreaker installs the circuit breaker around upstream calls.
+// It should be called once at startup, before the server accepts requests.
+func SetCircuitBreaker(b *breaker.Breaker) {
+	upstreamBreaker = b
+}
+
+// SetFieldTimeout sets how long loader-backed fields wait before resolving to nil with a
+// timeout error. It should be called once at startup, before the server accepts requests.
+func SetFieldTimeout(d time.Duration) {
+	fieldTimeout = d
+}
+
+// SetBatchWait sets how long dataloaders collect keys before fetching them. Longer waits add
+// latency but let fields resolved later in a large response join the same upstream call.
+// It should be called once at startup, before the server accepts requests.
+func SetBatchWait(d time.Duration) {
+	batchWait = d
+}
+
+// SetUpstreamTimeout sets the upper bound on how long a batch may wait on the upstream.
+// It should be called once at startup, before the server accepts requests.
+func SetUpstreamTimeout(d time.Duration) {
+	upstreamTimeout = d
+}
+
+// upstreamContext detaches the batch from the request's cancellation, so a result that
+// arrives after a field timed out (or after the response was sent) still reaches the
+// shared cache. The request deadline, if any, and the upstream timeout still apply.
+func upstreamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline := time.Now().Add(upstreamTimeout)
+	if requestDeadline, ok := ctx.Deadline(); ok && requestDeadline.Before(deadline) {
+		deadline = requestDeadline
 	}
+	return context.WithDeadline(context.WithoutCancel(ctx), deadline)
+}
 
-	// --- Fetch Missing Keys from Simulated API ---
-	if len(keysToFetchFromApi) > 0 {
-		log.Printf("Calling simulated API for keys: %v", keysToFetchFromApi)
-
-		// Simulate API latency only if we need to fetch
-		time.Sleep(500 * time.Millisecond)
-
-		// Simulate batch API response for the missing keys
-		apiResults := make([]*time.Time, len(keysToFetchFromApi))
-		// Simulate potential API errors (can be nil)
-		apiErrors := make([]error, len(keysToFetchFromApi))
-
-		for i, name := range keysToFetchFromApi {
-			// Deterministic logic for demo purposes
-			var date time.Time
-			if name == "AAPL" {
-				log.Printf("Simulating API fetch for %s", name)
-				date = time.Now().AddDate(0, 1, 0)
-			} else if name == "MSFT" {
-				log.Printf("Simulating API fetch for %s", name)
-				date = time.Now().AddDate(0, 2, 0)
-			} else if name == "GOOG" {
-				log.Printf("Simulating API fetch for %s", name)
-				date = time.Now().AddDate(0, 3, 0)
-			} else {
-				log.Printf("Simulating API fetch for %s", name)
-				date = time.Now().AddDate(0, 6, 0)
-			}
-			// Only store non-nil results in the results slice for the dataloader
-			if apiErrors[i] == nil {
-				apiResults[i] = &date
-				// Add successful results to the shared cache
-				log.Printf("Adding API result for %s to shared cache", name)
-				cache.Set(name, &date)
-			} else {
-				log.Printf("Simulated API error for %s: %v", name, apiErrors[i])
-			}
+// upstreamFailed reports whether a call failed because of the upstream itself, including
+// calls that ran out of time: upstreamContext detaches batches from request cancellation, so
+// a deadline error means the upstream was too slow. Malformed responses count too. Per-key
+// errors such as unknown symbols and cancellations by the caller don't.
+func upstreamFailed(errs []error) bool {
+	for _, err := range errs {
+		if errors.Is(err, context.Canceled) {
+			continue
 		}
-
-		// --- Populate main results slice from API results ---
-		for apiIdx, apiRes := range apiResults {
-			origIdx := apiFetchIndexToOrigIndex[apiIdx]
-			if apiErrors[apiIdx] != nil {
-				errors[origIdx] = apiErrors[apiIdx]
-			} else {
-				results[origIdx] = apiRes
-			}
+		if upstream.IsRetryable(err) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, upstream.ErrMalformedResponse) {
+			return true
 		}
 	}
+	return false
+}
+
+// errorsFor returns a slice with err repeated for each of n keys.
+func errorsFor(n int, err error) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
 
-	log.Printf("DataLoader Batch Function finished for keys: %v", symbolNames)
-	return results, errors
+// dividendDates fetches next ex-dividend dates, cached under the bare symbol.
+var dividendDates = &batch[string, *time.Time]{
+	name:   "dividend date",
+	encode: symbolKey,
+	fetch: func(ctx context.Context, keys []string) ([]*time.Time, []error) {
+		return dividendSource.FetchDividendDates(ctx, keys)
+	},
+	cache:    cache.DividendDates,
+	absent:   func(date *time.Time) bool { return date == nil },
+	override: dividendDateOverride,
+	call: func(ctx context.Context, keys []string) ([]*time.Time, []error) {
+		if dividendHedger != nil {
+			return dividendHedger.FetchDividendDates(ctx, keys)
+		}
+		return callUpstream(ctx, keys, dividendSource.FetchDividendDates)
+	},
 }
 
 // Context key for the loader
@@ -189,6 +241,21 @@ func Middleware(next http.Handler) http.Handler {
 	})
 }
 
+// AroundResponses gives every subscription event its own loader, so each event sees the
+// current shared cache and overrides. Queries keep the loader of their request.
+func AroundResponses(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	// Requests rejected before an operation was selected have no operation
+	if graphql.HasOperationContext(ctx) && isSubscription(graphql.GetOperationContext(ctx).Operation) {
+		ctx = context.WithValue(ctx, LoaderKey, NewDividendDateLoader())
+	}
+	return next(ctx)
+}
+
+// isSubscription reports whether op is a subscription. Op is nil for invalid requests.
+func isSubscription(op *ast.OperationDefinition) bool {
+	return op != nil && op.Operation == ast.Subscription
+}
+
 // For returns the loader from the context
 func For(ctx context.Context) *DividendDateLoader {
 	return ctx.Value(LoaderKey).(*DividendDateLoader)
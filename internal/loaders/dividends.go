@@ -0,0 +1,90 @@
+package loaders
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cache"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
+)
+
+// dividendDetailSource is the upstream API the dividend detail batch functions fetch from.
+var dividendDetailSource upstream.DividendSource = upstream.NewSimulatedSource()
+
+// SetDividendSource replaces the upstream source used for next dividends and dividend history.
+// It should be called once at startup, before the server accepts requests.
+func SetDividendSource(src upstream.DividendSource) {
+	dividendDetailSource = src
+}
+
+// nextDividends fetches the next declared dividend of each symbol.
+var nextDividends = &batch[string, *upstream.Dividend]{
+	name: "next dividend",
+	fetch: func(ctx context.Context, keys []string) ([]*upstream.Dividend, []error) {
+		return dividendDetailSource.FetchNextDividends(ctx, keys)
+	},
+	encode: symbolKey,
+	cache:  cache.NewNamespace[*upstream.Dividend]("nextDividend"),
+	absent: func(d *upstream.Dividend) bool { return d == nil },
+}
+
+// historyKey is the composite loader key of a page of dividend history.
+type historyKey struct {
+	Symbol string
+	Range  upstream.HistoryRange
+}
+
+// dividendHistories fetches pages of dividend history. Pages asking for the same range
+// are fetched in one upstream call, whatever their symbol, and each page is cached apart.
+var dividendHistories = &batch[historyKey, *upstream.HistoryPage]{
+	name:   "dividend history",
+	fetch:  fetchHistoryPages,
+	encode: func(k historyKey) string { return k.Symbol + "|" + encodeRange(k.Range) },
+	group:  func(k historyKey) string { return encodeRange(k.Range) },
+	cache:  cache.NewNamespace[*upstream.HistoryPage]("dividendHistory"),
+	absent: func(p *upstream.HistoryPage) bool { return p == nil },
+}
+
+// fetchHistoryPages fetches history pages that share their range in one call.
+func fetchHistoryPages(ctx context.Context, keys []historyKey) ([]*upstream.HistoryPage, []error) {
+	symbols := make([]string, len(keys))
+	for i, key := range keys {
+		symbols[i] = key.Symbol
+	}
+	return dividendDetailSource.FetchDividendHistory(ctx, keys[0].Range, symbols)
+}
+
+// encodeRange returns the canonical form of a history range.
+func encodeRange(r upstream.HistoryRange) string {
+	return strings.Join([]string{
+		formatBound(r.Start),
+		formatBound(r.End),
+		strconv.Itoa(r.Limit),
+		strconv.FormatBool(r.FromEnd),
+	}, "|")
+}
+
+// formatBound encodes a range bound, with the empty string for none.
+func formatBound(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// LoadNextDividend loads the next declared dividend for a symbol.
+func (d *DividendDateLoader) LoadNextDividend(ctx context.Context, symbolName string) (*upstream.Dividend, error) {
+	if d.actions != nil {
+		return loadAction[*upstream.Dividend](ctx, d.actions, upstream.ActionNextDividend, symbolName)
+	}
+	return d.nextDividends.load(ctx, symbolName)
+}
+
+// LoadDividendHistory loads one page of dividend history.
+func (d *DividendDateLoader) LoadDividendHistory(ctx context.Context, symbolName string, r upstream.HistoryRange) (*upstream.HistoryPage, error) {
+	// Equal ranges must make equal keys, whatever the time zone they were given in
+	r.Start, r.End = r.Start.UTC(), r.End.UTC()
+	return d.histories.load(ctx, historyKey{Symbol: symbolName, Range: r})
+}
@@ -0,0 +1,90 @@
+package loaders
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cache"
+)
+
+// DividendDateOverride is a dividend date set by an operator, served instead of the shared
+// cache and the upstream until it expires or is invalidated.
+type DividendDateOverride struct {
+	Symbol string
+	Date   time.Time
+	// ExpiresAt is when the upstream takes over again. Nil keeps the override until invalidated.
+	ExpiresAt *time.Time
+	// SetBy is the subject of the principal who set the override.
+	SetBy string
+	SetAt time.Time
+}
+
+// active reports whether the override still applies at now.
+func (o DividendDateOverride) active(now time.Time) bool {
+	return o.ExpiresAt == nil || now.Before(*o.ExpiresAt)
+}
+
+// dividendOverrides holds the dividend date overrides by symbol.
+var dividendOverrides = struct {
+	sync.Mutex
+	bySymbol map[string]DividendDateOverride
+}{bySymbol: make(map[string]DividendDateOverride)}
+
+// OverrideDividendDate pins the dividend date of a symbol. The cached date is dropped, so
+// the upstream is asked again once the override expires.
+func OverrideDividendDate(o DividendDateOverride) {
+	dividendOverrides.Lock()
+	dividendOverrides.bySymbol[o.Symbol] = o
+	dividendOverrides.Unlock()
+	cache.DividendDates.Delete(o.Symbol)
+	log.Printf("Dividend date of %s overridden with %s by %s", o.Symbol, o.Date.Format(time.DateOnly), o.SetBy)
+}
+
+// InvalidateDividendDates drops the cached dividend dates and overrides of symbols, so
+// they are fetched from the upstream on their next use.
+func InvalidateDividendDates(symbols []string) {
+	dividendOverrides.Lock()
+	for _, symbol := range symbols {
+		delete(dividendOverrides.bySymbol, symbol)
+	}
+	dividendOverrides.Unlock()
+	for _, symbol := range symbols {
+		cache.DividendDates.Delete(symbol)
+	}
+	log.Printf("Dividend dates of %v invalidated", symbols)
+}
+
+// InvalidateAllCaches drops everything in the shared cache, including the last known values
+// kept for the circuit breaker. Overrides are kept.
+func InvalidateAllCaches() {
+	cache.Flush()
+	log.Printf("Shared cache flushed")
+}
+
+// dividendDateOverride returns the overridden date of a symbol, if it has an override that
+// hasn't expired.
+func dividendDateOverride(symbol string) (*time.Time, bool) {
+	o, found := activeDividendDateOverride(symbol)
+	if !found {
+		return nil, false
+	}
+	date := o.Date
+	return &date, true
+}
+
+// activeDividendDateOverride returns the override of a symbol, if it has one that hasn't expired.
+func activeDividendDateOverride(symbol string) (DividendDateOverride, bool) {
+	dividendOverrides.Lock()
+	defer dividendOverrides.Unlock()
+	o, found := dividendOverrides.bySymbol[symbol]
+	if !found {
+		return DividendDateOverride{}, false
+	}
+	if !o.active(time.Now()) {
+		delete(dividendOverrides.bySymbol, symbol)
+		log.Printf("Dividend date override of %s expired", symbol)
+		return DividendDateOverride{}, false
+	}
+	return o, true
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// metric is a single series rendered by the registry.
+type metric interface {
+	name() string
+	help() string
+	kind() string
+	value() float64
+}
+
+// registry holds every metric exposed on the /metrics endpoint.
+type registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// defaultRegistry is the package-level registry all metrics register with.
+var defaultRegistry = &registry{metrics: make(map[string]metric)}
+
+// register adds m to the registry, panicking on duplicate names like expvar does.
+func (r *registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.metrics[m.name()]; exists {
+		panic(fmt.Sprintf("metrics: duplicate metric %q", m.name()))
+	}
+	r.metrics[m.name()] = m
+}
+
+// Counter is a monotonically increasing count.
+type Counter struct {
+	n, h string
+	v    atomic.Uint64
+}
+
+// NewCounter creates and registers a counter.
+func NewCounter(name, help string) *Counter {
+	c := &Counter{n: name, h: help}
+	defaultRegistry.register(c)
+	return c
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() { c.v.Add(1) }
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) { c.v.Add(n) }
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 { return c.v.Load() }
+
+func (c *Counter) name() string   { return c.n }
+func (c *Counter) help() string   { return c.h }
+func (c *Counter) kind() string   { return "counter" }
+func (c *Counter) value() float64 { return float64(c.v.Load()) }
+
+// gaugeFunc is a gauge whose value is read from a callback at scrape time.
+type gaugeFunc struct {
+	n, h string
+	fn   func() float64
+}
+
+// NewGaugeFunc registers a gauge reporting the value returned by fn.
+func NewGaugeFunc(name, help string, fn func() float64) {
+	defaultRegistry.register(&gaugeFunc{n: name, h: help, fn: fn})
+}
+
+func (g *gaugeFunc) name() string   { return g.n }
+func (g *gaugeFunc) help() string   { return g.h }
+func (g *gaugeFunc) kind() string   { return "gauge" }
+func (g *gaugeFunc) value() float64 { return g.fn() }
+
+// Handler serves every registered metric in the Prometheus text exposition format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defaultRegistry.mu.Lock()
+		names := make([]string, 0, len(defaultRegistry.metrics))
+		for name := range defaultRegistry.metrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		metrics := make([]metric, len(names))
+		for i, name := range names {
+			metrics[i] = defaultRegistry.metrics[name]
+		}
+		defaultRegistry.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, m := range metrics {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name(), m.help(), m.name(), m.kind(), m.name(), m.value())
+		}
+	})
+}
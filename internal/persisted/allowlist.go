@@ -0,0 +1,77 @@
+package persisted
+
+import (
+	"context"
+	"log"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/metrics"
+)
+
+var rejections = metrics.NewCounter("persisted_query_rejections_total",
+	"Operations rejected because they are not in the persisted query manifest.")
+
+// AllowList is a gqlgen extension that only executes the operations of a manifest. Clients
+// send either the hash of an operation, in the APQ persistedQuery extension, or its exact
+// text. Nothing can be registered at runtime.
+type AllowList struct {
+	queries map[string]string
+}
+
+var _ interface {
+	graphql.OperationParameterMutator
+	graphql.HandlerExtension
+} = AllowList{}
+
+// NewAllowList returns an allow-list of the operations of m.
+func NewAllowList(m *Manifest) AllowList {
+	queries := make(map[string]string, len(m.Operations))
+	for _, op := range m.Operations {
+		queries[op.ID] = op.Body
+	}
+	return AllowList{queries: queries}
+}
+
+// ExtensionName implements graphql.HandlerExtension.
+func (a AllowList) ExtensionName() string {
+	return "PersistedQueryAllowList"
+}
+
+// Validate implements graphql.HandlerExtension.
+func (a AllowList) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationParameters implements graphql.OperationParameterMutator. It replaces a
+// hash with the operation it stands for, and rejects operations missing from the manifest.
+func (a AllowList) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	hash := ""
+	if ext, ok := rawParams.Extensions["persistedQuery"].(map[string]any); ok {
+		hash, _ = ext["sha256Hash"].(string)
+	}
+
+	if rawParams.Query == "" {
+		query, found := a.queries[hash]
+		if !found {
+			rejections.Inc()
+			// The APQ code makes APQ clients send the full text, which is then checked too
+			err := gqlerror.Errorf("PersistedQueryNotFound")
+			errcode.Set(err, "PERSISTED_QUERY_NOT_FOUND")
+			return err
+		}
+		rawParams.Query = query
+		return nil
+	}
+
+	if computed := Hash(rawParams.Query); (hash != "" && hash != computed) || a.queries[computed] == "" {
+		rejections.Inc()
+		log.Printf("Rejected operation %q missing from the persisted query manifest", rawParams.OperationName)
+		err := gqlerror.Errorf("operation is not in the persisted query manifest")
+		errcode.Set(err, "PERSISTED_QUERY_NOT_ALLOWED")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package persisted
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ManifestFormat identifies persisted query manifest files.
+const ManifestFormat = "persisted-query-manifest"
+
+// Manifest lists the operations clients may execute in allow-list mode.
+type Manifest struct {
+	Format     string      `json:"format"`
+	Version    int         `json:"version"`
+	Operations []Operation `json:"operations"`
+}
+
+// Operation is one pre-registered document.
+type Operation struct {
+	// ID is the persisted query hash of Body, see Hash.
+	ID string `json:"id"`
+	// Name is the name of the operation, for humans.
+	Name string `json:"name"`
+	// Type is query, mutation or subscription.
+	Type string `json:"type"`
+	// Body is the exact text clients send.
+	Body string `json:"body"`
+	// Source is where the operation was extracted from.
+	Source string `json:"source,omitempty"`
+}
+
+// LoadManifest reads a manifest file, checking that every ID matches its body.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if m.Format != ManifestFormat || m.Version != 1 {
+		return nil, fmt.Errorf("%s is not a version 1 %s", path, ManifestFormat)
+	}
+	for i, op := range m.Operations {
+		if Hash(op.Body) != op.ID {
+			return nil, fmt.Errorf("%s: operation %d (%s) has an id that doesn't match its body", path, i, op.Name)
+		}
+	}
+	return &m, nil
+}
@@ -0,0 +1,53 @@
+// Package persisted serves persisted queries: automatic persisted queries (APQ) registered
+// by clients at runtime, and a manifest of pre-registered operations that can be enforced
+// as an allow-list in production.
+package persisted
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cache"
+)
+
+// QueryStore keeps the queries registered through APQ by hash. Any gqlgen cache can be used.
+type QueryStore = graphql.Cache[string]
+
+// NewLRUStore returns a store keeping the size most recently used queries. It is separate
+// from the shared cache, so registered queries survive cache invalidations and can't grow
+// the shared cache. Clients register a query again when it was evicted.
+func NewLRUStore(size int) QueryStore {
+	return lru.New[string](size)
+}
+
+// cacheStore keeps queries in a namespace of the shared cache.
+type cacheStore struct {
+	queries cache.Namespace[string]
+}
+
+// NewCacheStore returns a store keeping queries in a namespace of the shared cache, for the
+// namespace TTL. Unlike the LRU store, it isn't bounded in size, and invalidateAllCaches
+// drops the registered queries with everything else. Clients register them again.
+func NewCacheStore(queries cache.Namespace[string]) QueryStore {
+	return cacheStore{queries: queries}
+}
+
+// Get implements graphql.Cache.
+func (s cacheStore) Get(_ context.Context, hash string) (string, bool) {
+	return s.queries.Get(hash)
+}
+
+// Add implements graphql.Cache.
+func (s cacheStore) Add(_ context.Context, hash string, query string) {
+	s.queries.Set(hash, query)
+}
+
+// Hash returns the persisted query hash of a query: the hex encoded SHA-256 of its exact text.
+func Hash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
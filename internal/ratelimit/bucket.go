@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket is a token bucket refilled at a constant rate up to a burst size.
+// Tokens may be reserved ahead of time, in which case the balance goes negative
+// and later callers wait longer.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full bucket refilled at rate tokens per second.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last update. Callers must hold mu.
+func (b *TokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	b.tokens += elapsed * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// delay returns how long it takes for the balance to climb back to zero. Callers must hold mu.
+func (b *TokenBucket) delay() time.Duration {
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Allow takes a token if one is available. Otherwise it reports how long
+// the caller should wait before retrying.
+func (b *TokenBucket) Allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	// Time until a whole token is available
+	b.tokens--
+	wait := b.delay()
+	b.tokens++
+	return false, wait
+}
+
+// Wait reserves a token and blocks until it becomes available. If the wait would
+// exceed maxWait the reservation is dropped and the required wait is returned with
+// ErrLimited. If ctx is done first the token is handed back and ctx.Err() is returned.
+func (b *TokenBucket) Wait(ctx context.Context, maxWait time.Duration) (time.Duration, error) {
+	b.mu.Lock()
+	b.refill(time.Now())
+	b.tokens--
+	wait := b.delay()
+	if wait > maxWait {
+		b.tokens++
+		b.mu.Unlock()
+		return wait, ErrLimited
+	}
+	b.mu.Unlock()
+
+	if wait == 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return 0, nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+// idleFor reports how long the bucket has not been used.
+func (b *TokenBucket) idleFor(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Sub(b.last)
+}
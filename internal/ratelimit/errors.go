@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrLimited is returned by TokenBucket.Wait when the wait would be too long.
+var ErrLimited = errors.New("rate limited")
+
+// Scopes of the limits that can reject a request.
+const (
+	ScopeClient   = "client"
+	ScopeUpstream = "upstream"
+)
+
+// Error is returned when a client rate limit or the upstream quota is exhausted.
+type Error struct {
+	// Scope is the limit that was hit, ScopeClient or ScopeUpstream.
+	Scope string
+	// RetryAfter is how long until the request can succeed again.
+	RetryAfter time.Duration
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s rate limit exceeded, retry after %ds", e.Scope, retryAfterSeconds(e.RetryAfter))
+}
+
+// GraphQLError converts the error into a GraphQL error for the current field, with
+// extensions.code RATE_LIMITED and the retry-after hint in seconds.
+func (e *Error) GraphQLError(ctx context.Context) *gqlerror.Error {
+	var path ast.Path
+	if graphql.GetFieldContext(ctx) != nil {
+		path = graphql.GetPath(ctx)
+	}
+	return &gqlerror.Error{
+		Err:     e,
+		Message: e.Error(),
+		Path:    path,
+		Extensions: map[string]any{
+			"code":       "RATE_LIMITED",
+			"scope":      e.Scope,
+			"retryAfter": e.RetryAfterSeconds(),
+		},
+	}
+}
+
+// RetryAfterSeconds returns RetryAfter rounded up to whole seconds, at least one.
+func (e *Error) RetryAfterSeconds() int {
+	return retryAfterSeconds(e.RetryAfter)
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds, as used by the Retry-After header.
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// sweepInterval is how often idle client buckets are looked for.
+	sweepInterval = time.Minute
+	// idleTimeout is how long a client bucket is kept after its last use.
+	idleTimeout = 10 * time.Minute
+)
+
+// KeyedLimiter keeps one token bucket per client key.
+type KeyedLimiter struct {
+	rate  float64
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*TokenBucket
+	lastSweep time.Time
+}
+
+// NewKeyedLimiter creates a limiter allowing each key rate requests per second
+// with bursts of up to burst requests.
+func NewKeyedLimiter(rate float64, burst int) *KeyedLimiter {
+	return &KeyedLimiter{
+		rate:      rate,
+		burst:     burst,
+		buckets:   make(map[string]*TokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket for key. When the bucket is empty it
+// returns false along with the time until the next token is available.
+func (l *KeyedLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = NewTokenBucket(l.rate, l.burst)
+		l.buckets[key] = bucket
+	}
+	l.mu.Unlock()
+
+	return bucket.Allow()
+}
+
+// sweep drops buckets that have been idle long enough to be full again. Callers must hold mu.
+func (l *KeyedLimiter) sweep(now time.Time) {
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		if bucket.idleFor(now) > idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+}
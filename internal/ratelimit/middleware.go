@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/auth"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/batching"
+)
+
+// Context keys for the client address and websocket flag
+type contextKey string
+
+const (
+	clientIPKey  = contextKey("clientIP")
+	websocketKey = contextKey("websocket")
+)
+
+// Middleware enforces the per-client rate limit on HTTP requests. It must run after the
+// auth middleware so authenticated clients are limited by principal instead of by IP.
+// A websocket upgrade is charged as a single request; operations sent over the
+// connection are limited by AroundOperations once the connection principal is known.
+func Middleware(limiter *KeyedLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey, clientIP(r))
+		if websocket.IsWebSocketUpgrade(r) {
+			ctx = context.WithValue(ctx, websocketKey, true)
+		}
+
+		key := clientKey(ctx)
+		if ok, retryAfter := limiter.Allow(key); !ok {
+			log.Printf("Rate limit exceeded for %s", key)
+			writeLimited(w, &Error{Scope: ScopeClient, RetryAfter: retryAfter})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AroundOperations returns a gqlgen operation middleware that applies the per-client limit
+// to every operation started over a websocket connection, and to every operation of a
+// batch after the first. Other HTTP operations are skipped because Middleware already
+// charged their request, which pays for the first operation of a batch.
+func AroundOperations(limiter *KeyedLimiter) graphql.OperationMiddleware {
+	return func(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+		isWebsocket, _ := ctx.Value(websocketKey).(bool)
+		index, batched := batching.OperationIndex(ctx)
+		if !isWebsocket && (!batched || index == 0) {
+			return next(ctx)
+		}
+
+		key := clientKey(ctx)
+		if ok, retryAfter := limiter.Allow(key); !ok {
+			log.Printf("Rate limit exceeded for %s", key)
+			err := &Error{Scope: ScopeClient, RetryAfter: retryAfter}
+			return graphql.OneShot(&graphql.Response{Errors: gqlerror.List{err.GraphQLError(ctx)}})
+		}
+		return next(ctx)
+	}
+}
+
+// clientKey identifies the caller by principal when authenticated, otherwise by IP.
+func clientKey(ctx context.Context) string {
+	if principal := auth.PrincipalFrom(ctx); principal != nil && principal.Method != auth.MethodAnonymous {
+		return "principal:" + principal.Subject
+	}
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return "ip:" + ip
+}
+
+// clientIP returns the host part of the request's remote address.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeLimited sends a 429 response with a Retry-After header and a GraphQL error body.
+func writeLimited(w http.ResponseWriter, limitErr *Error) {
+	gqlErr := limitErr.GraphQLError(context.Background())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(limitErr.RetryAfter)))
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(&graphql.Response{Errors: gqlerror.List{gqlErr}})
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Quota is a global budget for calls to an upstream API shared by every request.
+// Callers queue for up to maxWait when the budget is exhausted and are rejected after that.
+type Quota struct {
+	bucket  *TokenBucket
+	maxWait time.Duration
+}
+
+// NewQuota creates a quota of rate calls per second with bursts of up to burst calls.
+// A maxWait of zero rejects immediately instead of queueing.
+func NewQuota(rate float64, burst int, maxWait time.Duration) *Quota {
+	return &Quota{bucket: NewTokenBucket(rate, burst), maxWait: maxWait}
+}
+
+// Acquire takes one call from the budget, waiting if allowed. It returns an *Error
+// when the budget is exhausted, or the context error if ctx is done while queued.
+func (q *Quota) Acquire(ctx context.Context) error {
+	wait := q.maxWait
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		// Don't queue past the request deadline
+		wait = time.Until(deadline)
+	}
+
+	retryAfter, err := q.bucket.Wait(ctx, wait)
+	if err == ErrLimited {
+		log.Printf("Upstream quota exhausted, retry after %s", retryAfter)
+		return &Error{Scope: ScopeUpstream, RetryAfter: retryAfter}
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+// Package requestid tags every HTTP request with an ID, returned in the X-Request-ID response
+// header and logged with the request's internal errors, so reports can be matched to logs.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header carries the request ID. An ID sent by the client, e.g. set by a proxy, is kept.
+const Header = "X-Request-ID"
+
+// maxLength bounds the length of the IDs accepted from clients.
+const maxLength = 64
+
+type contextKey struct{}
+
+// Middleware adds the request ID to the context and the response headers.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = New()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, id)))
+	})
+}
+
+// FromContext returns the ID of the request, or "" outside of a request.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// New returns a random request ID.
+func New() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// valid reports whether a client's ID is safe to log: short, and only made of letters,
+// digits, dashes, dots and underscores.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '.', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/catalog"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph/model"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/symbols"
+)
+
+const (
+	// defaultSearchResults and maxSearchResults bound the first argument of Query.searchSymbols.
+	defaultSearchResults = 10
+	maxSearchResults     = 50
+)
+
+// symbolCatalog holds the symbol reference data. Nil behaves like an empty catalog.
+var symbolCatalog *catalog.Catalog
+
+// SetCatalog installs the symbol reference catalog.
+// It should be called once at startup, before the server accepts requests.
+func SetCatalog(c *catalog.Catalog) {
+	symbolCatalog = c
+}
+
+// SymbolImpl provides the implementation logic for the Query.symbol resolver.
+func SymbolImpl(ctx context.Context, name string) (*model.SymbolDefinition, error) {
+	log.Printf("Query.symbol called for %q", name)
+
+	symbol, err := symbols.Normalize(name)
+	if err != nil {
+		return nil, inputError(ctx, "name", err)
+	}
+
+	entry := symbolCatalog.Lookup(symbol)
+	if entry == nil {
+		return nil, nil
+	}
+	return fromEntry(entry), nil
+}
+
+// SearchSymbolsImpl provides the implementation logic for the Query.searchSymbols resolver.
+func SearchSymbolsImpl(ctx context.Context, prefix string, first *int32) ([]*model.SymbolDefinition, error) {
+	log.Printf("Query.searchSymbols called for %q", prefix)
+
+	limit := defaultSearchResults
+	if first != nil {
+		limit = int(*first)
+	}
+	if limit < 0 || limit > maxSearchResults {
+		return nil, inputError(ctx, "first", errors.New("first must be between 0 and 50"))
+	}
+
+	entries := symbolCatalog.Search(prefix, limit)
+	result := make([]*model.SymbolDefinition, len(entries))
+	for i, entry := range entries {
+		result[i] = fromEntry(entry)
+	}
+	return result, nil
+}
+
+// knownSymbol reports whether a normalised symbol is in the catalog. Without a catalog, every
+// symbol is known.
+func knownSymbol(name string) bool {
+	return symbolCatalog == nil || symbolCatalog.Lookup(name) != nil
+}
+
+// newSymbolDefinition creates the definition of a normalised symbol, with its reference data
+// filled in from the catalog when the catalog knows it.
+func newSymbolDefinition(name string) *model.SymbolDefinition {
+	if entry := symbolCatalog.Lookup(name); entry != nil {
+		return fromEntry(entry)
+	}
+	return &model.SymbolDefinition{Name: name}
+}
+
+// fromEntry converts a catalog entry to its GraphQL representation.
+func fromEntry(entry *catalog.Entry) *model.SymbolDefinition {
+	assetType := model.AssetType(entry.AssetType)
+	return &model.SymbolDefinition{
+		Name:        entry.Symbol,
+		Description: optionalString(entry.Description),
+		Exchange:    optionalString(entry.Exchange),
+		AssetType:   &assetType,
+		Currency:    optionalString(entry.Currency),
+		Isin:        optionalString(entry.ISIN),
+	}
+}
+
+// optionalString returns nil for an empty string.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,63 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph/model"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/loaders"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
+)
+
+// NextEarningsDate resolves the nextEarningsDate field for the SymbolDefinition type.
+func NextEarningsDate(ctx context.Context, obj *model.SymbolDefinition, singleFlight *bool) (*time.Time, error) {
+	date, err := loaders.For(ctx).LoadNextEarningsDate(ctx, obj.Name)
+	if err != nil {
+		return nil, err
+	}
+	return date, nil
+}
+
+// NextSplit resolves the nextSplit field for the SymbolDefinition type.
+func NextSplit(ctx context.Context, obj *model.SymbolDefinition, singleFlight *bool) (*model.Split, error) {
+	split, err := loaders.For(ctx).LoadNextSplit(ctx, obj.Name)
+	if err != nil {
+		return nil, err
+	}
+	if split == nil {
+		return nil, nil
+	}
+	return toModelSplit(*split), nil
+}
+
+// Splits resolves the splits field for the SymbolDefinition type.
+// The whole history is loaded and cached per symbol, then filtered to the requested range.
+func Splits(ctx context.Context, obj *model.SymbolDefinition, from, to *time.Time, singleFlight *bool) ([]*model.Split, error) {
+	history, err := loaders.For(ctx).LoadSplits(ctx, obj.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*model.Split, 0, len(history))
+	for _, split := range history {
+		if from != nil && split.ExDate.Before(*from) {
+			continue
+		}
+		if to != nil && split.ExDate.After(*to) {
+			continue
+		}
+		result = append(result, toModelSplit(split))
+	}
+	return result, nil
+}
+
+// toModelSplit converts an upstream split into the GraphQL model.
+func toModelSplit(s upstream.Split) *model.Split {
+	return &model.Split{
+		ExDate:           s.ExDate,
+		Numerator:        int32(s.Numerator),
+		Denominator:      int32(s.Denominator),
+		Ratio:            float64(s.Numerator) / float64(s.Denominator),
+		AnnouncementDate: s.AnnouncementDate,
+	}
+}
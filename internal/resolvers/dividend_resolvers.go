@@ -0,0 +1,121 @@
+package resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph/model"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/loaders"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/upstream"
+)
+
+// NextDividend resolves the nextDividend field for the SymbolDefinition type.
+func NextDividend(ctx context.Context, obj *model.SymbolDefinition, singleFlight *bool) (*model.Dividend, error) {
+	dividend, err := loaders.For(ctx).LoadNextDividend(ctx, obj.Name)
+	if err != nil {
+		return nil, err
+	}
+	if dividend == nil {
+		return nil, nil
+	}
+	return toModelDividend(*dividend), nil
+}
+
+// DividendHistory resolves the dividendHistory field for the SymbolDefinition type.
+// The date filters and cursors are turned into one range, so every page is a separate
+// loader key, fetched in one upstream call with the same page of other symbols.
+func DividendHistory(ctx context.Context, obj *model.SymbolDefinition, from, to *time.Time, first *int32, after *string, last *int32, before *string, singleFlight *bool) (*model.DividendConnection, error) {
+	var r upstream.HistoryRange
+	if from != nil {
+		r.Start = *from
+	}
+	if to != nil {
+		// to is inclusive, the range end isn't
+		r.End = to.Add(time.Nanosecond)
+	}
+
+	var err error
+	switch {
+	case first != nil && last != nil:
+		return nil, inputError(ctx, "last", errFirstAndLast)
+	case last != nil:
+		r.FromEnd = true
+		if r.Limit, err = pageSize("last", last); err != nil {
+			return nil, inputError(ctx, "last", err)
+		}
+	default:
+		if r.Limit, err = pageSize("first", first); err != nil {
+			return nil, inputError(ctx, "first", err)
+		}
+	}
+
+	// Cursors narrow the range, they are exclusive
+	if after != nil {
+		exDate, err := decodeDividendCursor(*after)
+		if err != nil {
+			return nil, inputError(ctx, "after", err)
+		}
+		if start := exDate.Add(time.Nanosecond); start.After(r.Start) {
+			r.Start = start
+		}
+	}
+	if before != nil {
+		exDate, err := decodeDividendCursor(*before)
+		if err != nil {
+			return nil, inputError(ctx, "before", err)
+		}
+		if r.End.IsZero() || exDate.Before(r.End) {
+			r.End = exDate
+		}
+	}
+
+	page, err := loaders.For(ctx).LoadDividendHistory(ctx, obj.Name, r)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		return nil, nil
+	}
+
+	conn := &model.DividendConnection{
+		Edges: make([]*model.DividendEdge, len(page.Dividends)),
+		PageInfo: &model.PageInfo{
+			// Only the direction being paged in is known without another upstream call
+			HasNextPage:     page.HasMore && !r.FromEnd,
+			HasPreviousPage: page.HasMore && r.FromEnd,
+		},
+	}
+	for i, dividend := range page.Dividends {
+		conn.Edges[i] = &model.DividendEdge{
+			Cursor: encodeDividendCursor(dividend.ExDate),
+			Node:   toModelDividend(dividend),
+		}
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	return conn, nil
+}
+
+// singleFlightOrDefault returns the singleFlight argument, which defaults to true.
+func singleFlightOrDefault(singleFlight *bool) bool {
+	if singleFlight == nil {
+		return true
+	}
+	return *singleFlight
+}
+
+// toModelDividend converts an upstream dividend into the GraphQL model.
+func toModelDividend(d upstream.Dividend) *model.Dividend {
+	return &model.Dividend{
+		Amount:          d.Amount,
+		Currency:        d.Currency,
+		ExDate:          d.ExDate,
+		RecordDate:      d.RecordDate,
+		PayDate:         d.PayDate,
+		DeclarationDate: d.DeclarationDate,
+		Frequency:       model.DividendFrequency(d.Frequency),
+		Yield:           d.Yield,
+	}
+}
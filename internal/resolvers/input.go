@@ -0,0 +1,57 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/apierror"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/symbols"
+)
+
+// normalizeNames normalises the names argument of a field. Every invalid symbol is reported
+// as an INVALID_SYMBOL error on the field, and the last one is returned to fail the field.
+func normalizeNames(ctx context.Context, names []string) ([]string, error) {
+	normalized, errs := symbols.NormalizeAll(names)
+	if len(errs) == 0 {
+		return normalized, nil
+	}
+
+	indexes := make([]int, 0, len(errs))
+	for i := range errs {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	var last *gqlerror.Error
+	for _, i := range indexes {
+		if last != nil {
+			graphql.AddError(ctx, last)
+		}
+		last = inputError(ctx, "names", errs[i])
+		last.Extensions["index"] = i
+	}
+	return nil, last
+}
+
+// inputError reports an invalid field argument on the field, as an INVALID_SYMBOL error for
+// malformed symbols and a BAD_USER_INPUT error otherwise.
+func inputError(ctx context.Context, argument string, err error) *gqlerror.Error {
+	code := "BAD_USER_INPUT"
+	var symbolErr *symbols.Error
+	if errors.As(err, &symbolErr) {
+		code = string(apierror.InvalidSymbol)
+	}
+	return &gqlerror.Error{
+		Err:     err,
+		Message: err.Error(),
+		Path:    graphql.GetPath(ctx),
+		Extensions: map[string]any{
+			"code":     code,
+			"argument": argument,
+		},
+	}
+}
@@ -0,0 +1,82 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/audit"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/auth"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/events"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph/model"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/loaders"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/symbols"
+)
+
+// InvalidateDividendDateImpl provides the implementation logic for the
+// Mutation.invalidateDividendDate resolver.
+func InvalidateDividendDateImpl(ctx context.Context, names []string) ([]string, error) {
+	names, err := normalizeNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Mutation.invalidateDividendDate called for %v", names)
+
+	loaders.InvalidateDividendDates(names)
+	audit.Record(ctx, "invalidateDividendDate", names, nil)
+	for _, name := range names {
+		events.Publish(events.Change{Symbol: name, Reason: "invalidation"})
+	}
+	return names, nil
+}
+
+// InvalidateAllCachesImpl provides the implementation logic for the
+// Mutation.invalidateAllCaches resolver.
+func InvalidateAllCachesImpl(ctx context.Context) (bool, error) {
+	log.Printf("Mutation.invalidateAllCaches called")
+
+	loaders.InvalidateAllCaches()
+	audit.Record(ctx, "invalidateAllCaches", nil, nil)
+	events.Publish(events.Change{Reason: "invalidation"})
+	return true, nil
+}
+
+// OverrideDividendDateImpl provides the implementation logic for the
+// Mutation.overrideDividendDate resolver.
+func OverrideDividendDateImpl(ctx context.Context, name string, date time.Time, expiresAt *time.Time) (*model.DividendDateOverride, error) {
+	symbol, err := symbols.Normalize(name)
+	if err != nil {
+		return nil, inputError(ctx, "name", err)
+	}
+	now := time.Now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, inputError(ctx, "expiresAt", errors.New("expiresAt must be in the future"))
+	}
+	log.Printf("Mutation.overrideDividendDate called for %s", symbol)
+
+	override := loaders.DividendDateOverride{Symbol: symbol, Date: date.UTC(), SetBy: "unknown", SetAt: now}
+	if expiresAt != nil {
+		expires := expiresAt.UTC()
+		override.ExpiresAt = &expires
+	}
+	if principal := auth.PrincipalFrom(ctx); principal != nil {
+		override.SetBy = principal.Subject
+	}
+	loaders.OverrideDividendDate(override)
+
+	details := map[string]any{"date": override.Date}
+	if override.ExpiresAt != nil {
+		details["expiresAt"] = *override.ExpiresAt
+	}
+	audit.Record(ctx, "overrideDividendDate", []string{symbol}, details)
+	events.Publish(events.Change{Symbol: symbol, Reason: "override"})
+
+	return &model.DividendDateOverride{
+		Symbol:    override.Symbol,
+		Date:      override.Date,
+		ExpiresAt: override.ExpiresAt,
+		SetBy:     override.SetBy,
+		SetAt:     override.SetAt,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package resolvers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultPageSize is the page size when neither first nor last is given.
+	defaultPageSize = 20
+	// maxPageSize is the largest page a client may ask for.
+	maxPageSize = 100
+	// dividendCursorPrefix marks cursors pointing at a dividend, by ex-dividend date.
+	dividendCursorPrefix = "dividend:"
+)
+
+// errFirstAndLast is returned when a connection is paged in both directions at once.
+var errFirstAndLast = errors.New("first and last can't be used together")
+
+// encodeDividendCursor returns the opaque cursor of a dividend.
+func encodeDividendCursor(exDate time.Time) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(dividendCursorPrefix + exDate.UTC().Format(time.RFC3339Nano)))
+}
+
+// decodeDividendCursor returns the ex-dividend date a cursor points at.
+func decodeDividendCursor(cursor string) (time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), dividendCursorPrefix) {
+		return time.Time{}, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	exDate, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(string(raw), dividendCursorPrefix))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return exDate, nil
+}
+
+// pageSize validates a first or last argument.
+func pageSize(argument string, size *int32) (int, error) {
+	if size == nil {
+		return defaultPageSize, nil
+	}
+	if *size < 0 || *size > maxPageSize {
+		return 0, fmt.Errorf("%s must be between 0 and %d", argument, maxPageSize)
+	}
+	return int(*size), nil
+}
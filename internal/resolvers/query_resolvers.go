@@ -2,8 +2,14 @@ package resolvers
 
 import (
 	"context"
+	"fmt"
 	"log"
 
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/apierror"
 	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph/model"
 )
 
@@ -11,15 +17,38 @@ import (
 func SymbolsImpl(ctx context.Context, names []string) ([]*model.SymbolDefinition, error) {
 	log.Printf("Query.symbols called with %d symbols", len(names))
 
+	// Normalise names so that spelling variants share loader keys and cache entries
+	names, err := normalizeNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create symbol definitions for each name
 	result := make([]*model.SymbolDefinition, len(names))
 	for i, name := range names {
-		// Here we create model objects with just the name filled in
-		// NextExDividendDate will be resolved separately when requested
-		result[i] = &model.SymbolDefinition{
-			Name: name,
+		// Names missing from the catalog are null, with an error of their own
+		if !knownSymbol(name) {
+			graphql.AddError(ctx, notFoundError(ctx, i, name))
+			continue
 		}
+		// Here we create model objects with just the reference data filled in
+		// NextExDividendDate will be resolved separately when requested
+		result[i] = newSymbolDefinition(name)
 	}
 
 	return result, nil
 }
+
+// notFoundError reports a name of the names argument missing from the catalog, on the
+// path of its item in the result, which gives its index.
+func notFoundError(ctx context.Context, index int, name string) *gqlerror.Error {
+	path := append(ast.Path{}, graphql.GetPath(ctx)...)
+	return &gqlerror.Error{
+		Message: fmt.Sprintf("symbol %s not found", name),
+		Path:    append(path, ast.PathIndex(index)),
+		Extensions: map[string]any{
+			"code":   string(apierror.NotFound),
+			"symbol": name,
+		},
+	}
+}
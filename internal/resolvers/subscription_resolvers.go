@@ -5,19 +5,36 @@ import (
 	"log"
 	"time"
 
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/auth"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/events"
 	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph/model"
 )
 
 // SymbolUpdatesImpl provides the implementation logic for the Subscription.symbolUpdates resolver.
 func SymbolUpdatesImpl(ctx context.Context, names []string) (<-chan *model.SymbolDefinition, error) {
-	log.Printf("Subscription.symbolUpdates called with %d symbols", len(names))
+	// The principal was attached to the connection context by the websocket InitFunc
+	subject := "unknown"
+	if principal := auth.PrincipalFrom(ctx); principal != nil {
+		subject = principal.Subject
+	}
+	log.Printf("Subscription.symbolUpdates called with %d symbols by %s", len(names), subject)
+
+	// Normalise names so that spelling variants share loader keys and cache entries
+	names, err := normalizeNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a channel to send updates
 	ch := make(chan *model.SymbolDefinition, 1)
 
+	// Changes made by operators are pushed as soon as they happen
+	changes, unsubscribe := events.Subscribe(names)
+
 	// Start a goroutine to send periodic updates
 	go func() {
 		defer close(ch)
+		defer unsubscribe()
 
 		// Keep track of the current index in the names slice
 		index := 0
@@ -36,19 +53,51 @@ func SymbolUpdatesImpl(ctx context.Context, names []string) (<-chan *model.Symbo
 				name := names[index]
 
 				// Create a symbol definition (NextExDividendDate will be resolved downstream)
-				symbol := &model.SymbolDefinition{
-					Name: name,
+				// and send it to the channel
+				log.Printf("Sending update for symbol %s to %s", name, subject)
+				if !sendUpdate(ctx, ch, name) {
+					return
 				}
 
-				// Send it to the channel
-				log.Printf("Sending update for symbol %s", name)
-				ch <- symbol
-
 				// Move to the next name (round-robin)
 				index = (index + 1) % len(names)
+			case change := <-changes:
+				for _, name := range changedNames(names, change) {
+					log.Printf("Sending %s change of symbol %s to %s", change.Reason, name, subject)
+					if !sendUpdate(ctx, ch, name) {
+						return
+					}
+				}
 			}
 		}
 	}()
 
 	return ch, nil
 }
+
+// sendUpdate sends the definition of a symbol to a subscription, reporting false if the
+// subscription ended first.
+func sendUpdate(ctx context.Context, ch chan<- *model.SymbolDefinition, name string) bool {
+	select {
+	case ch <- newSymbolDefinition(name):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// changedNames returns the subscribed names a change applies to, each once.
+func changedNames(names []string, change events.Change) []string {
+	if change.Symbol != "" {
+		return []string{change.Symbol}
+	}
+	seen := make(map[string]bool, len(names))
+	var changed []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
@@ -4,28 +4,73 @@ import (
 	"context"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cachecontrol"
 	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/gen/graph/model"
 	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/loaders"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/responsecache"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/singleflight"
 )
 
 // NextExDividendDate resolves the NextExDividendDate field for the SymbolDefinition type.
 // This custom implementation is used by registering it with the ResolverRoot.
 // It replaces the auto-generated resolver.
 func NextExDividendDate(ctx context.Context, obj *model.SymbolDefinition, singleFlight *bool) (*time.Time, error) {
-	// Get the loader from context
+	// Get the loader from context. The @singleFlight directive has already suppressed repeats.
 	loader := loaders.For(ctx)
 
-	// Determine the singleFlight flag value (default to true if not specified)
-	shouldSingleFlight := true
-	if singleFlight != nil {
-		shouldSingleFlight = *singleFlight
-	}
+	// A cached response carrying this date is dropped when the date is invalidated
+	responsecache.DependsOn(ctx, obj.Name)
 
-	// Load the dividend date using the loader, passing the singleFlight flag
-	dateResult, err := loader.LoadDividendDate(ctx, obj.Name, shouldSingleFlight)
+	// Load the dividend date using the loader
+	dateResult, err := loader.LoadDividendDate(ctx, obj.Name)
 	if err != nil {
 		return nil, err
 	}
 
+	// A cached response must not outlive the date it carries
+	restrictToDividendDate(ctx, obj.Name)
+
 	return dateResult, nil // Loader now returns *time.Time directly
 }
+
+// NextExDividendDateResult resolves the nextExDividendDateResult field for the SymbolDefinition
+// type. Unlike NextExDividendDate, it tells a repeat suppressed by singleFlight apart from a
+// symbol without an upcoming dividend. The field is non-null, so the @singleFlight directive
+// doesn't apply to it, and it tracks repeats in the same EVENT scope itself. Load errors are
+// added to the response errors, and the result has the ERROR status instead of being null.
+func NextExDividendDateResult(ctx context.Context, obj *model.SymbolDefinition, singleFlight *bool) (*model.DividendDateResult, error) {
+	if singleflight.Repeated(ctx, obj, singleflight.Event, singleFlightOrDefault(singleFlight)) {
+		return &model.DividendDateResult{Status: model.DividendDateStatusSuppressedDuplicate}, nil
+	}
+	responsecache.DependsOn(ctx, obj.Name)
+
+	date, err := loaders.For(ctx).LoadDividendDate(ctx, obj.Name)
+	if err != nil {
+		graphql.AddError(ctx, err)
+		return &model.DividendDateResult{Status: model.DividendDateStatusError}, nil
+	}
+	restrictToDividendDate(ctx, obj.Name)
+
+	if date == nil {
+		return &model.DividendDateResult{Status: model.DividendDateStatusNotAvailable}, nil
+	}
+	return &model.DividendDateResult{Date: date, Status: model.DividendDateStatusResolved}, nil
+}
+
+// restrictToDividendDate keeps a cached response from outliving the dividend date of a symbol.
+func restrictToDividendDate(ctx context.Context, symbolName string) {
+	if ttl, expires := loaders.DividendDateTTL(symbolName); expires {
+		cachecontrol.Restrict(ctx, ttl, cachecontrol.Public)
+	}
+}
+
+// ParentIdentity identifies the parent objects of @singleFlight fields: a symbol definition by
+// its name, so a field is resolved once per symbol however often the symbol is listed.
+func ParentIdentity(obj any) (string, bool) {
+	if symbol, ok := obj.(*model.SymbolDefinition); ok && symbol != nil {
+		return "SymbolDefinition:" + symbol.Name, true
+	}
+	return "", false
+}
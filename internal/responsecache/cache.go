@@ -0,0 +1,166 @@
+// Package responsecache caches whole query responses, so identical repeated operations
+// skip execution entirely. Responses are cached for the max-age computed from their
+// @cacheControl hints, and dropped when the dividend dates they carry are invalidated.
+package responsecache
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/auth"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cachecontrol"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/events"
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/metrics"
+)
+
+var (
+	hits = metrics.NewCounter("response_cache_hits_total",
+		"Query responses served from the response cache without execution.")
+	misses = metrics.NewCounter("response_cache_misses_total",
+		"Queries executed because the response cache had no fresh response for them.")
+)
+
+// Cache is an operation-level response cache. Invalidation is tracked with generation
+// counters: invalidating a symbol bumps its generation, which makes every response that
+// depended on an older generation stale without finding them in the store.
+type Cache struct {
+	store Store
+
+	mu         sync.Mutex
+	generation uint64
+	symbols    map[string]uint64
+}
+
+// New returns a response cache keeping its responses in store.
+func New(store Store) *Cache {
+	return &Cache{store: store, symbols: make(map[string]uint64)}
+}
+
+// Invalidate drops the cached responses depending on the symbol of a change, or every
+// response for a change of every symbol. It is meant to be registered with events.Listen.
+func (c *Cache) Invalidate(change events.Change) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if change.Symbol == "" {
+		c.generation++
+		log.Printf("Response cache invalidated after %s", change.Reason)
+		return
+	}
+	c.symbols[change.Symbol]++
+	log.Printf("Responses depending on %s invalidated after %s", change.Symbol, change.Reason)
+}
+
+// fresh reports whether no invalidation happened since the entry was built.
+func (c *Cache) fresh(entry *Entry) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry.generation != c.generation {
+		return false
+	}
+	for symbol, generation := range entry.symbols {
+		if c.symbols[symbol] != generation {
+			return false
+		}
+	}
+	return true
+}
+
+// recording collects what an executing operation depends on.
+type recording struct {
+	cache      *Cache
+	mu         sync.Mutex
+	generation uint64
+	symbols    map[string]uint64
+}
+
+type contextKey struct{}
+
+// DependsOn records that the response being resolved carries data of symbol, so it is
+// dropped from the cache when the symbol is invalidated. Resolvers call it before loading.
+func DependsOn(ctx context.Context, symbol string) {
+	rec, _ := ctx.Value(contextKey{}).(*recording)
+	if rec == nil {
+		return
+	}
+	rec.cache.mu.Lock()
+	generation := rec.cache.symbols[symbol]
+	rec.cache.mu.Unlock()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if _, seen := rec.symbols[symbol]; !seen {
+		rec.symbols[symbol] = generation
+	}
+}
+
+// AroundOperations returns a gqlgen operation middleware serving queries from the cache,
+// and caching the responses of queries that may be cached. Mutations and subscriptions
+// are always executed.
+func (c *Cache) AroundOperations(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	opCtx := graphql.GetOperationContext(ctx)
+	if opCtx.Operation == nil || opCtx.Operation.Operation != ast.Query {
+		return next(ctx)
+	}
+	privateKey, publicKey, ok := keys(opCtx, auth.PrincipalFrom(ctx))
+	if !ok {
+		return next(ctx)
+	}
+
+	// A principal's own responses take precedence over the ones shared with its roles
+	for _, key := range []string{privateKey, publicKey} {
+		if entry, found := c.store.Get(key); found && c.fresh(entry) {
+			hits.Inc()
+			log.Printf("Response cache HIT for operation %q", opCtx.OperationName)
+			// Let HTTP caching know how long the cached response has left
+			cachecontrol.Restrict(ctx, time.Until(entry.Expires), entry.Scope)
+			return graphql.OneShot(&graphql.Response{Data: entry.Data, Extensions: entry.Extensions})
+		}
+	}
+	misses.Inc()
+
+	// Collect the cache hints of the response, even for POST queries
+	policy := cachecontrol.FromContext(ctx)
+	if policy == nil {
+		ctx, policy = cachecontrol.WithPolicy(ctx)
+	}
+	c.mu.Lock()
+	rec := &recording{cache: c, generation: c.generation, symbols: make(map[string]uint64)}
+	c.mu.Unlock()
+	ctx = context.WithValue(ctx, contextKey{}, rec)
+
+	handler := next(ctx)
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+		if resp == nil {
+			return nil
+		}
+		maxAge, scope := policy.MaxAge()
+		// Payloads of @defer and @stream operations are only parts of the response
+		if len(resp.Errors) > 0 || resp.HasNext != nil || maxAge < time.Second {
+			return resp
+		}
+
+		key := publicKey
+		if scope == cachecontrol.Private {
+			key = privateKey
+		}
+		rec.mu.Lock()
+		entry := &Entry{
+			Data:       resp.Data,
+			Extensions: resp.Extensions,
+			Scope:      scope,
+			Expires:    time.Now().Add(maxAge),
+			generation: rec.generation,
+			symbols:    rec.symbols,
+		}
+		rec.mu.Unlock()
+		c.store.Set(key, entry, maxAge)
+		log.Printf("Response cache stored operation %q for %s", opCtx.OperationName, maxAge)
+		return resp
+	}
+}
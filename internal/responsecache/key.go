@@ -0,0 +1,71 @@
+package responsecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/lexer"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/auth"
+)
+
+// keys returns the cache keys of an operation: the private key, shared only by requests of
+// the same principal, and the public key, shared by principals holding the same roles.
+// Both are hashes of the normalised document, the operation name and the variables.
+func keys(opCtx *graphql.OperationContext, principal *auth.Principal) (private, public string, ok bool) {
+	document, ok := normalize(opCtx.RawQuery)
+	if !ok {
+		return "", "", false
+	}
+	// Map keys are sorted when encoding, so equal variables always encode the same
+	variables, err := json.Marshal(opCtx.Variables)
+	if err != nil {
+		return "", "", false
+	}
+
+	operation := document + "\x00" + opCtx.OperationName + "\x00" + string(variables)
+	subject, roles := "", ""
+	if principal != nil {
+		subject = string(principal.Method) + ":" + principal.Subject
+		sorted := append([]string(nil), principal.Roles...)
+		sort.Strings(sorted)
+		roles = string(principal.Method) + ":" + strings.Join(sorted, ",")
+	}
+	return hash("private\x00" + subject + "\x00" + operation), hash("public\x00" + roles + "\x00" + operation), true
+}
+
+// normalize returns the document with insignificant whitespace, commas and comments
+// removed, so formatting differences don't split the cache.
+func normalize(query string) (string, bool) {
+	l := lexer.New(&ast.Source{Input: query})
+	var b strings.Builder
+	for {
+		token, err := l.ReadToken()
+		if err != nil {
+			return "", false
+		}
+		switch token.Kind {
+		case lexer.EOF:
+			return b.String(), true
+		case lexer.Comment:
+			continue
+		case lexer.String, lexer.BlockString:
+			b.WriteString(strconv.Quote(token.Value))
+		default:
+			b.WriteString(token.Value)
+		}
+		b.WriteByte(' ')
+	}
+}
+
+// hash returns the hex encoded SHA-256 of s.
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,61 @@
+package responsecache
+
+import (
+	"encoding/json"
+	"time"
+
+	gocache "github.com/patrickmn/go-cache"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/cachecontrol"
+)
+
+// Entry is a cached response.
+type Entry struct {
+	Data       json.RawMessage
+	Extensions map[string]any
+	// Scope is the cache scope of the response: private responses are cached per principal.
+	Scope   cachecontrol.Scope
+	Expires time.Time
+	// generation and symbols record the invalidation generations the response was built at,
+	// so invalidations made since make it stale.
+	generation uint64
+	symbols    map[string]uint64
+}
+
+// Store holds cached responses. It is the extension point for other backends.
+type Store interface {
+	Get(key string) (*Entry, bool)
+	// Set stores an entry until ttl elapsed.
+	Set(key string, entry *Entry, ttl time.Duration)
+	Delete(key string)
+}
+
+// MemoryStore keeps responses in memory.
+type MemoryStore struct {
+	c *gocache.Cache
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{c: gocache.New(gocache.NoExpiration, time.Minute)}
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(key string) (*Entry, bool) {
+	v, found := m.c.Get(key)
+	if !found {
+		return nil, false
+	}
+	entry, ok := v.(*Entry)
+	return entry, ok
+}
+
+// Set implements Store.
+func (m *MemoryStore) Set(key string, entry *Entry, ttl time.Duration) {
+	m.c.Set(key, entry, ttl)
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(key string) {
+	m.c.Delete(key)
+}
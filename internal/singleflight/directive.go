@@ -0,0 +1,152 @@
+// Package singleflight implements the @singleFlight schema directive: fields carrying it are
+// resolved once per parent object within a scope, and repeats resolve to null. The loaders
+// only batch and cache; which selections reach them is decided here.
+package singleflight
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// argumentName is the field argument overriding the directive's default per selection.
+const argumentName = "singleFlight"
+
+// IdentityFunc returns the key identifying a parent object, or false for parents without one.
+type IdentityFunc func(obj any) (string, bool)
+
+// identity identifies parent objects. Nil identifies every parent by its path.
+var identity IdentityFunc
+
+// SetIdentity sets how parent objects are identified, so a field is resolved once for an
+// object wherever it appears in the response.
+// It should be called once at startup, before the server accepts requests.
+func SetIdentity(fn IdentityFunc) {
+	identity = fn
+}
+
+type contextKey Scope
+
+// Directive implements the @singleFlight(scope: ..., default: ...) schema directive.
+func Directive(ctx context.Context, obj any, next graphql.Resolver, scope *Scope, defaultArg *bool) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc.Field.Definition.Type.NonNull {
+		return next(ctx)
+	}
+
+	trackerScope := Request
+	if scope != nil {
+		trackerScope = *scope
+	}
+	if Repeated(ctx, obj, trackerScope, enabled(fc, defaultArg)) {
+		return nil, nil
+	}
+	return next(ctx)
+}
+
+// Repeated marks the field being resolved on obj as attempted in scope, and reports whether it
+// already was when singleFlight is set. Selections with singleFlight unset are marked too, so
+// a later selection with it set is a repeat of theirs. It lets fields the directive is ignored
+// on, like non-null ones, tell a repeat apart in their value. Outside of a scope, nothing is a
+// repeat.
+func Repeated(ctx context.Context, obj any, scope Scope, singleFlight bool) bool {
+	tracker, _ := ctx.Value(contextKey(scope)).(*AttemptTracker)
+	if tracker == nil {
+		return false
+	}
+
+	key := fieldKey(ctx, obj)
+	if !tracker.MarkAttempted(key) {
+		log.Printf("%s first attempt in this %s scope, marked", key, scope)
+		return false
+	}
+	if !singleFlight {
+		log.Printf("%s already resolved in this %s scope with singleFlight=false, resolving again", key, scope)
+		return false
+	}
+	log.Printf("%s already resolved in this %s scope with singleFlight=true, returning nil", key, scope)
+	return true
+}
+
+// enabled reports whether singleFlight applies to a field: as set by its singleFlight
+// argument, or by the directive's default.
+func enabled(fc *graphql.FieldContext, defaultArg *bool) bool {
+	if arg, ok := fc.Args[argumentName].(*bool); ok && arg != nil {
+		return *arg
+	}
+	return defaultArg == nil || *defaultArg
+}
+
+// fieldKey identifies the field being resolved: its parent, its name and its arguments other
+// than singleFlight, so each page of a paginated field is tracked on its own.
+func fieldKey(ctx context.Context, obj any) string {
+	fc := graphql.GetFieldContext(ctx)
+	key := parentKey(fc, obj) + "." + fc.Field.Name
+
+	var vars map[string]any
+	if graphql.HasOperationContext(ctx) {
+		vars = graphql.GetOperationContext(ctx).Variables
+	}
+	args := fc.Field.ArgumentMap(vars)
+	delete(args, argumentName)
+	if len(args) == 0 {
+		return key
+	}
+
+	// Sorted, so equal arguments make equal keys
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%s:%v", name, args[name])
+	}
+	return key + "(" + strings.Join(names, ",") + ")"
+}
+
+// parentKey identifies the parent object of a field.
+func parentKey(fc *graphql.FieldContext, obj any) string {
+	if identity != nil {
+		if key, ok := identity(obj); ok {
+			return key
+		}
+	}
+	if fc.Parent == nil {
+		return fc.Object
+	}
+	return fmt.Sprintf("%s@%s", fc.Object, fc.Parent.Path())
+}
+
+// Middleware remembers attempts in the Connection scope for the duration of an HTTP request,
+// which is the whole connection for websockets.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextKey(Connection), NewAttemptTracker())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AroundOperations is a gqlgen operation middleware remembering attempts in the Request scope
+// for the duration of each operation.
+func AroundOperations(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	return next(context.WithValue(ctx, contextKey(Request), NewAttemptTracker()))
+}
+
+// AroundResponses is a gqlgen response middleware remembering attempts in the Event scope for
+// each subscription event. Queries and mutations use their Request scope.
+func AroundResponses(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	tracker, _ := ctx.Value(contextKey(Request)).(*AttemptTracker)
+	if graphql.HasOperationContext(ctx) {
+		if op := graphql.GetOperationContext(ctx).Operation; op != nil && op.Operation == ast.Subscription {
+			tracker = NewAttemptTracker()
+		}
+	}
+	return next(context.WithValue(ctx, contextKey(Event), tracker))
+}
@@ -0,0 +1,62 @@
+package singleflight
+
+import (
+	"context"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// selection returns the context resolving NextExDividendDate of a symbol, with its singleFlight
+// argument set to arg, or omitted when nil.
+func selection(ctx context.Context, arg *bool) context.Context {
+	args := map[string]any{}
+	if arg != nil {
+		args[argumentName] = arg
+	}
+	return graphql.WithFieldContext(ctx, &graphql.FieldContext{
+		Object: "SymbolDefinition",
+		Field: graphql.CollectedField{Field: &ast.Field{
+			Name:       "NextExDividendDate",
+			Alias:      "NextExDividendDate",
+			Definition: &ast.FieldDefinition{Name: "NextExDividendDate", Type: ast.NamedType("Date", nil)},
+		}},
+		Args: args,
+	})
+}
+
+func TestDirectiveRecordsEverySelection(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name         string
+		singleFlight []*bool
+		want         []bool
+	}{
+		{name: "true then true", singleFlight: []*bool{&on, &on}, want: []bool{true, false}},
+		{name: "default then default", singleFlight: []*bool{nil, nil}, want: []bool{true, false}},
+		{name: "false then true", singleFlight: []*bool{&off, &on}, want: []bool{true, false}},
+		{name: "true then false", singleFlight: []*bool{&on, &off}, want: []bool{true, true}},
+		{name: "false then false", singleFlight: []*bool{&off, &off}, want: []bool{true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := Event
+			ctx := context.WithValue(context.Background(), contextKey(Event), NewAttemptTracker())
+			for i, arg := range tt.singleFlight {
+				resolved := false
+				_, err := Directive(selection(ctx, arg), nil, func(context.Context) (any, error) {
+					resolved = true
+					return nil, nil
+				}, &scope, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resolved != tt.want[i] {
+					t.Errorf("selection %d resolved = %t, want %t", i, resolved, tt.want[i])
+				}
+			}
+		})
+	}
+}
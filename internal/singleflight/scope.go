@@ -0,0 +1,40 @@
+package singleflight
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Scope is how long attempts are remembered.
+type Scope string
+
+const (
+	// Request remembers attempts for an operation: a query, a batched operation, or a whole
+	// subscription.
+	Request Scope = "REQUEST"
+	// Event remembers attempts for one subscription event. For queries, it is Request.
+	Event Scope = "EVENT"
+	// Connection remembers attempts for a websocket connection, or one HTTP request.
+	Connection Scope = "CONNECTION"
+)
+
+// MarshalGQL implements graphql.Marshaler for the SingleFlightScope enum.
+func (s Scope) MarshalGQL(w io.Writer) {
+	_, _ = io.WriteString(w, strconv.Quote(string(s)))
+}
+
+// UnmarshalGQL implements graphql.Unmarshaler for the SingleFlightScope enum.
+func (s *Scope) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("SingleFlightScope must be a string")
+	}
+	switch scope := Scope(str); scope {
+	case Request, Event, Connection:
+		*s = scope
+		return nil
+	default:
+		return fmt.Errorf("%s is not a valid SingleFlightScope", str)
+	}
+}
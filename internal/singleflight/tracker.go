@@ -0,0 +1,33 @@
+package singleflight
+
+import "sync"
+
+// AttemptTracker tracks which keys have been attempted in a scope.
+// Fields resolve concurrently, so it is safe for concurrent use.
+type AttemptTracker struct {
+	mu        sync.Mutex
+	attempted map[string]bool
+}
+
+// NewAttemptTracker creates a new tracker
+func NewAttemptTracker() *AttemptTracker {
+	return &AttemptTracker{
+		attempted: make(map[string]bool),
+	}
+}
+
+// IsAttempted checks if a key has been attempted
+func (t *AttemptTracker) IsAttempted(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.attempted[key]
+}
+
+// MarkAttempted marks a key as attempted and reports whether it already was
+func (t *AttemptTracker) MarkAttempted(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	attempted := t.attempted[key]
+	t.attempted[key] = true
+	return attempted
+}
@@ -0,0 +1,106 @@
+// Package symbols normalises and validates the ticker symbols clients ask for, so that
+// "aapl", " AAPL" and "AAPL.US" share one loader key and one shared cache entry.
+package symbols
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// maxRootLength is the longest ticker accepted, not counting the exchange suffix.
+	maxRootLength = 10
+	// maxExchangeLength is the longest exchange suffix accepted.
+	maxExchangeLength = 4
+	// DefaultExchange is the suffix that is dropped, since unsuffixed symbols already mean it.
+	DefaultExchange = "US"
+)
+
+// Error reports a symbol that can't be normalised.
+type Error struct {
+	// Input is the symbol as the client sent it.
+	Input string
+	// Reason says what is wrong with it.
+	Reason string
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid symbol %q: %s", e.Input, e.Reason)
+}
+
+// Normalize returns the canonical form of a symbol: surrounding whitespace is trimmed, letters
+// are uppercased, and an optional exchange suffix is kept except for the default exchange
+// (so "aapl.us" becomes "AAPL" and "vod.l" becomes "VOD.L"). The ticker itself is made of
+// letters and digits, with "-" separating a share class as in "BRK-B".
+func Normalize(raw string) (string, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(raw))
+	if symbol == "" {
+		return "", &Error{Input: raw, Reason: "symbol is empty"}
+	}
+
+	root, exchange, hasExchange := strings.Cut(symbol, ".")
+	if err := checkRoot(root); err != "" {
+		return "", &Error{Input: raw, Reason: err}
+	}
+	if !hasExchange {
+		return root, nil
+	}
+
+	if exchange == "" || len(exchange) > maxExchangeLength || !isLetters(exchange) {
+		return "", &Error{Input: raw, Reason: fmt.Sprintf("exchange suffix must be 1 to %d letters", maxExchangeLength)}
+	}
+	if exchange == DefaultExchange {
+		return root, nil
+	}
+	return root + "." + exchange, nil
+}
+
+// NormalizeAll normalises each symbol, returning the error for every symbol that is invalid.
+// The result has the same length and order as the input.
+func NormalizeAll(raw []string) ([]string, map[int]error) {
+	normalized := make([]string, len(raw))
+	var errs map[int]error
+	for i, symbol := range raw {
+		n, err := Normalize(symbol)
+		if err != nil {
+			if errs == nil {
+				errs = make(map[int]error)
+			}
+			errs[i] = err
+			continue
+		}
+		normalized[i] = n
+	}
+	return normalized, errs
+}
+
+// checkRoot validates the ticker part of a symbol, returning the reason it is invalid, if any.
+func checkRoot(root string) string {
+	switch {
+	case root == "":
+		return "ticker is empty"
+	case len(root) > maxRootLength:
+		return fmt.Sprintf("ticker is longer than %d characters", maxRootLength)
+	case root[0] < 'A' || root[0] > 'Z':
+		return "ticker must start with a letter"
+	case strings.HasSuffix(root, "-") || strings.Contains(root, "--"):
+		return "share class separator must be followed by a class"
+	}
+	for _, r := range root {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return fmt.Sprintf("unexpected character %q", r)
+		}
+	}
+	return ""
+}
+
+// isLetters reports whether s consists only of uppercase ASCII letters.
+func isLetters(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
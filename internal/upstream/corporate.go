@@ -0,0 +1,59 @@
+package upstream
+
+import (
+	"context"
+	"time"
+)
+
+// Split is a stock split. A reverse split has a Numerator smaller than its Denominator.
+type Split struct {
+	ExDate time.Time
+	// Numerator new shares replace every Denominator old shares, e.g. 4 for 1.
+	Numerator   int
+	Denominator int
+	// AnnouncementDate is nil when the upstream doesn't know it.
+	AnnouncementDate *time.Time
+}
+
+// EarningsSource fetches earnings dates for a batch of symbols from an upstream API.
+// Implementations return one result and one error per requested symbol, in request order.
+type EarningsSource interface {
+	// FetchNextEarningsDates returns the next scheduled earnings date of each symbol, or nil if none is scheduled.
+	FetchNextEarningsDates(ctx context.Context, symbols []string) ([]*time.Time, []error)
+}
+
+// SplitSource fetches stock splits for a batch of symbols from an upstream API.
+// Implementations return one result and one error per requested symbol, in request order.
+type SplitSource interface {
+	// FetchNextSplits returns the next announced split of each symbol, or nil if none is announced.
+	FetchNextSplits(ctx context.Context, symbols []string) ([]*Split, []error)
+	// FetchSplits returns the past splits of each symbol, oldest first.
+	FetchSplits(ctx context.Context, symbols []string) ([][]Split, []error)
+}
+
+// ActionField selects one kind of data from a CorporateActionsSource.
+type ActionField string
+
+const (
+	ActionNextDividend     ActionField = "nextDividend"
+	ActionNextEarningsDate ActionField = "nextEarningsDate"
+	ActionNextSplit        ActionField = "nextSplit"
+	ActionSplits           ActionField = "splits"
+)
+
+// CorporateActions is what a combined corporate actions call returned for one symbol.
+// Fields that weren't asked for are left empty.
+type CorporateActions struct {
+	NextDividend     *Dividend
+	NextEarningsDate *time.Time
+	NextSplit        *Split
+	// Splits are the past splits, oldest first.
+	Splits []Split
+}
+
+// CorporateActionsSource fetches dividends, earnings and splits together, for upstreams
+// exposing them through one endpoint. Implementations return one result and one error per
+// requested symbol, in request order.
+type CorporateActionsSource interface {
+	FetchCorporateActions(ctx context.Context, symbols []string, fields []ActionField) ([]*CorporateActions, []error)
+}
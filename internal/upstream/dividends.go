@@ -0,0 +1,86 @@
+package upstream
+
+import (
+	"context"
+	"time"
+)
+
+// Frequency is how often a dividend is paid.
+type Frequency string
+
+const (
+	FrequencyMonthly    Frequency = "MONTHLY"
+	FrequencyQuarterly  Frequency = "QUARTERLY"
+	FrequencySemiAnnual Frequency = "SEMI_ANNUAL"
+	FrequencyAnnual     Frequency = "ANNUAL"
+	FrequencyIrregular  Frequency = "IRREGULAR"
+)
+
+// Dividend is a single declared or historical dividend payment.
+type Dividend struct {
+	// Amount is paid per share, in Currency.
+	Amount   float64
+	Currency string
+	ExDate   time.Time
+	// RecordDate, PayDate and DeclarationDate are nil when the upstream doesn't know them.
+	RecordDate      *time.Time
+	PayDate         *time.Time
+	DeclarationDate *time.Time
+	Frequency       Frequency
+	// Yield is the annualised dividend yield as a fraction, e.g. 0.012 for 1.2%.
+	Yield *float64
+}
+
+// DividendSource fetches dividend details for a batch of symbols from an upstream API.
+// Implementations return one result and one error per requested symbol, in request order.
+type DividendSource interface {
+	// FetchNextDividends returns the next upcoming dividend of each symbol, or nil if none is declared.
+	FetchNextDividends(ctx context.Context, symbols []string) ([]*Dividend, []error)
+	// FetchDividendHistory returns the same page of past dividends for each symbol.
+	FetchDividendHistory(ctx context.Context, r HistoryRange, symbols []string) ([]*HistoryPage, []error)
+}
+
+// HistoryRange selects a page of past dividends by ex-dividend date. Like the parameters
+// of a real history API, it is shared by every symbol in a call.
+type HistoryRange struct {
+	// Start and End bound the ex-dividend dates to [Start, End). The zero time is unbounded.
+	Start time.Time
+	End   time.Time
+	// Limit is the page size.
+	Limit int
+	// FromEnd takes the page from the end of the range instead of the start.
+	FromEnd bool
+}
+
+// HistoryPage is one page of past dividends, oldest first.
+type HistoryPage struct {
+	Dividends []Dividend
+	// HasMore reports whether the range holds more dividends beyond the page, past its
+	// end for a page taken from the start and before its start for one taken from the end.
+	HasMore bool
+}
+
+// Page cuts the page selected by r out of history, which must be sorted oldest first.
+func (r HistoryRange) Page(history []Dividend) *HistoryPage {
+	var inRange []Dividend
+	for _, dividend := range history {
+		if !r.Start.IsZero() && dividend.ExDate.Before(r.Start) {
+			continue
+		}
+		if !r.End.IsZero() && !dividend.ExDate.Before(r.End) {
+			continue
+		}
+		inRange = append(inRange, dividend)
+	}
+
+	page := &HistoryPage{Dividends: inRange}
+	if len(inRange) > r.Limit {
+		page.HasMore = true
+		if r.FromEnd {
+			page.Dividends = inRange[len(inRange)-r.Limit:]
+		} else {
+			page.Dividends = inRange[:r.Limit]
+		}
+	}
+	return page
+}
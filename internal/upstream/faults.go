@@ -0,0 +1,152 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FaultConfig describes the failures injected by a FaultInjectingSource.
+// The zero value injects nothing.
+type FaultConfig struct {
+	// Latency is added to every call.
+	Latency Duration `json:"latency"`
+	// LatencyJitter adds a uniformly distributed extra delay of up to this much.
+	LatencyJitter Duration `json:"latencyJitter"`
+	// SpikeRate is the probability (0 to 1) that a call takes SpikeLatency instead,
+	// to model a long latency tail.
+	SpikeRate    float64  `json:"spikeRate"`
+	SpikeLatency Duration `json:"spikeLatency"`
+
+	// BatchFailureRate is the probability that a whole call fails with a transient error.
+	BatchFailureRate float64 `json:"batchFailureRate"`
+	// KeyErrorRate is the probability that each key fails with a transient error.
+	KeyErrorRate float64 `json:"keyErrorRate"`
+	// KeyErrors makes the listed symbols always fail with the given (non-transient) message.
+	KeyErrors map[string]string `json:"keyErrors,omitempty"`
+	// PartialRate is the probability that a symbol is silently left out of the response.
+	PartialRate float64 `json:"partialRate"`
+	// MismatchRate is the probability that one result of a call comes back under a symbol
+	// that wasn't requested, as an upstream changing the case of symbols would.
+	MismatchRate float64 `json:"mismatchRate"`
+}
+
+// FaultInjectingSource decorates a KeyedDividendDateSource with configurable latency and
+// failures, to check how the key alignment, loader, cache and error handling behave under
+// failure. The configuration can be changed while the server is running.
+type FaultInjectingSource struct {
+	source KeyedDividendDateSource
+
+	mu  sync.RWMutex
+	cfg FaultConfig
+}
+
+// NewFaultInjectingSource wraps source with the initial fault configuration.
+func NewFaultInjectingSource(source KeyedDividendDateSource, cfg FaultConfig) *FaultInjectingSource {
+	return &FaultInjectingSource{source: source, cfg: cfg}
+}
+
+// Config returns the current fault configuration.
+func (f *FaultInjectingSource) Config() FaultConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cfg
+}
+
+// SetConfig replaces the fault configuration. It applies to calls started afterwards.
+func (f *FaultInjectingSource) SetConfig(cfg FaultConfig) {
+	f.mu.Lock()
+	f.cfg = cfg
+	f.mu.Unlock()
+	log.Printf("Fault injection configuration updated: %+v", cfg)
+}
+
+// FetchKeyedDividendDates implements KeyedDividendDateSource.
+func (f *FaultInjectingSource) FetchKeyedDividendDates(ctx context.Context, symbols []string) ([]DividendDate, error) {
+	cfg := f.Config()
+
+	if delay := cfg.delay(); delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if chance(cfg.BatchFailureRate) {
+		log.Printf("Fault injection: failing whole batch %v", symbols)
+		return nil, fmt.Errorf("injected batch failure: %w", ErrTransient)
+	}
+
+	keyed, err := f.source.FetchKeyedDividendDates(ctx, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]DividendDate, 0, len(keyed))
+	for _, result := range keyed {
+		switch msg, ok := cfg.KeyErrors[result.Symbol]; {
+		case ok:
+			result = DividendDate{Symbol: result.Symbol, Err: errors.New(msg)}
+		case chance(cfg.KeyErrorRate):
+			result = DividendDate{Symbol: result.Symbol, Err: fmt.Errorf("injected error for %s: %w", result.Symbol, ErrTransient)}
+		case chance(cfg.PartialRate):
+			log.Printf("Fault injection: dropping %s from the response", result.Symbol)
+			continue
+		}
+		results = append(results, result)
+	}
+
+	if len(results) > 0 && chance(cfg.MismatchRate) {
+		last := &results[len(results)-1]
+		log.Printf("Fault injection: returning the result for %s under %s", last.Symbol, strings.ToLower(last.Symbol))
+		last.Symbol = strings.ToLower(last.Symbol)
+	}
+
+	return results, nil
+}
+
+// delay draws the latency of one call from the configured distribution.
+func (c FaultConfig) delay() time.Duration {
+	if chance(c.SpikeRate) {
+		return time.Duration(c.SpikeLatency)
+	}
+	d := time.Duration(c.Latency)
+	if c.LatencyJitter > 0 {
+		d += rand.N(time.Duration(c.LatencyJitter))
+	}
+	return d
+}
+
+// Duration is a time.Duration written in JSON as a string such as "250ms".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"250ms\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// chance returns true with probability p.
+func chance(p float64) bool {
+	return p > 0 && rand.Float64() < p
+}
@@ -0,0 +1,32 @@
+package upstream
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// AdminHandler serves the runtime fault configuration:
+// GET returns it, PUT replaces it with the JSON body, and DELETE clears every fault.
+func (f *FaultInjectingSource) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var cfg FaultConfig
+			if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+				http.Error(w, "invalid fault configuration: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.SetConfig(cfg)
+		case http.MethodDelete:
+			f.SetConfig(FaultConfig{})
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(f.Config())
+	})
+}
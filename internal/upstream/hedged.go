@@ -0,0 +1,170 @@
+package upstream
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/metrics"
+)
+
+const (
+	// latencySamples is how many recent call latencies the hedge delay is computed from.
+	latencySamples = 200
+	// minLatencySamples is how many latencies must be observed before hedging starts.
+	minLatencySamples = 20
+	// hedgeRateWindow is how many recent calls the hedge rate cap is measured over.
+	hedgeRateWindow = 100
+)
+
+var (
+	hedgesIssued = metrics.NewCounter("upstream_hedges_total",
+		"Duplicate upstream calls issued because the original was slower than the hedge delay.")
+	hedgeWins = metrics.NewCounter("upstream_hedge_wins_total",
+		"Hedged upstream calls that returned before the original.")
+)
+
+// HedgeConfig configures hedged upstream calls.
+type HedgeConfig struct {
+	// Percentile of recent call latencies (0 to 1) after which a duplicate call is issued.
+	Percentile float64
+	// MinDelay is the shortest hedge delay, whatever the observed latencies.
+	MinDelay time.Duration
+	// MaxHedgeRate caps the fraction of recent calls that may be hedged (0 to 1).
+	MaxHedgeRate float64
+}
+
+// HedgedSource decorates a DividendDateSource to cut tail latency. When a call hasn't
+// returned after the configured latency percentile, the same call is issued again and
+// whichever returns first is used; the other one is cancelled.
+type HedgedSource struct {
+	source DividendDateSource
+	cfg    HedgeConfig
+
+	mu        sync.Mutex
+	latencies []time.Duration // ring buffer of recent call latencies
+	nextIdx   int
+	hedged    [hedgeRateWindow]bool // ring buffer of whether recent calls were hedged
+	callIdx   int
+}
+
+// NewHedgedSource wraps source with hedging.
+func NewHedgedSource(source DividendDateSource, cfg HedgeConfig) *HedgedSource {
+	return &HedgedSource{source: source, cfg: cfg}
+}
+
+// hedgeResponse is the outcome of one of the racing calls.
+type hedgeResponse struct {
+	results []*time.Time
+	errors  []error
+	hedge   bool
+}
+
+// FetchDividendDates implements DividendDateSource.
+func (h *HedgedSource) FetchDividendDates(ctx context.Context, symbols []string) ([]*time.Time, []error) {
+	// Cancelling on return stops whichever call lost the race
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	start := time.Now()
+	responses := make(chan hedgeResponse, 2)
+	call := func(hedge bool) {
+		results, errs := h.source.FetchDividendDates(ctx, symbols)
+		responses <- hedgeResponse{results: results, errors: errs, hedge: hedge}
+	}
+	go call(false)
+
+	var hedgeTimer <-chan time.Time
+	if delay, ok := h.hedgeDelay(); ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedgeTimer = timer.C
+	}
+
+	inFlight, hedged := 1, false
+	for {
+		select {
+		case <-hedgeTimer:
+			hedgeTimer = nil
+			if h.allowHedge() {
+				log.Printf("Upstream call for %v slower than %s, issuing hedge", symbols, time.Since(start).Round(time.Millisecond))
+				hedgesIssued.Inc()
+				hedged = true
+				inFlight++
+				go call(true)
+			}
+		case resp := <-responses:
+			inFlight--
+			// A call that failed outright doesn't win while the other may still succeed
+			if inFlight > 0 && allFailed(resp.errors) {
+				continue
+			}
+			if resp.hedge {
+				log.Printf("Hedged upstream call for %v won", symbols)
+				hedgeWins.Inc()
+			}
+			h.record(time.Since(start), hedged)
+			return resp.results, resp.errors
+		}
+	}
+}
+
+// hedgeDelay returns the configured latency percentile, or false while too few
+// latencies have been observed to know what slow means.
+func (h *HedgedSource) hedgeDelay() (time.Duration, bool) {
+	h.mu.Lock()
+	samples := append([]time.Duration(nil), h.latencies...)
+	h.mu.Unlock()
+
+	if len(samples) < minLatencySamples {
+		return 0, false
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	idx := int(math.Ceil(h.cfg.Percentile*float64(len(samples)))) - 1
+	idx = max(0, min(idx, len(samples)-1))
+	return max(samples[idx], h.cfg.MinDelay), true
+}
+
+// allowHedge reports whether the hedge rate cap leaves room for another hedge.
+func (h *HedgedSource) allowHedge() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := 0
+	for _, wasHedged := range h.hedged {
+		if wasHedged {
+			count++
+		}
+	}
+	return float64(count+1) <= h.cfg.MaxHedgeRate*hedgeRateWindow
+}
+
+// record stores the latency of a finished call and whether it was hedged.
+func (h *HedgedSource) record(latency time.Duration, hedged bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.latencies) < latencySamples {
+		h.latencies = append(h.latencies, latency)
+	} else {
+		h.latencies[h.nextIdx] = latency
+		h.nextIdx = (h.nextIdx + 1) % latencySamples
+	}
+
+	h.hedged[h.callIdx] = hedged
+	h.callIdx = (h.callIdx + 1) % hedgeRateWindow
+}
+
+// allFailed reports whether every key of a call failed.
+func allFailed(errs []error) bool {
+	for _, err := range errs {
+		if err == nil {
+			return false
+		}
+	}
+	return len(errs) > 0
+}
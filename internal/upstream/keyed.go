@@ -0,0 +1,160 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mxcoppell/graphql-resolver-batch-cache/internal/metrics"
+)
+
+var keyMismatches = metrics.NewCounter("upstream_key_mismatches_total",
+	"Upstream results that were missing, unrequested or duplicated for a requested key.")
+
+// DividendDate is one keyed result from a KeyedDividendDateSource.
+type DividendDate struct {
+	Symbol string
+	Date   *time.Time
+	// Err is set when the upstream reported an error for this symbol.
+	Err error
+}
+
+// KeyedDividendDateSource fetches dividend dates from an upstream whose responses are keyed
+// by symbol rather than ordered like the request. A non-nil error fails the whole call.
+type KeyedDividendDateSource interface {
+	FetchKeyedDividendDates(ctx context.Context, symbols []string) ([]DividendDate, error)
+}
+
+// KeyError is the per-key error for a requested symbol that the upstream response
+// didn't answer exactly once. It is transient, so the key is retried.
+type KeyError struct {
+	Symbol string
+	Reason string
+}
+
+// Error implements error.
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("upstream response for %s: %s", e.Symbol, e.Reason)
+}
+
+// Unwrap marks key errors as transient.
+func (e *KeyError) Unwrap() error {
+	return ErrTransient
+}
+
+// Misalignment describes how an upstream response differed from the requested keys.
+type Misalignment struct {
+	// Missing are requested symbols without a result.
+	Missing []string
+	// Extra are returned symbols that weren't requested.
+	Extra []string
+	// Duplicate are requested symbols returned more than once with different results.
+	Duplicate []string
+}
+
+// Empty reports whether the response matched the requested keys.
+func (m Misalignment) Empty() bool {
+	return len(m.Missing) == 0 && len(m.Extra) == 0 && len(m.Duplicate) == 0
+}
+
+// String implements fmt.Stringer.
+func (m Misalignment) String() string {
+	return fmt.Sprintf("missing %v, extra %v, duplicate %v", m.Missing, m.Extra, m.Duplicate)
+}
+
+// Align orders keyed results like symbols. Each requested symbol gets exactly one result or
+// error: missing symbols and symbols returned more than once with different results get a
+// KeyError, and results for symbols that weren't requested are dropped. Symbols requested
+// more than once all get the same result.
+func Align(symbols []string, keyed []DividendDate) ([]*time.Time, []error, Misalignment) {
+	requested := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		requested[symbol] = true
+	}
+
+	var mismatch Misalignment
+	bySymbol := make(map[string]DividendDate, len(keyed))
+	conflicts := make(map[string]bool)
+	for _, result := range keyed {
+		if !requested[result.Symbol] {
+			mismatch.Extra = append(mismatch.Extra, result.Symbol)
+			continue
+		}
+		if previous, seen := bySymbol[result.Symbol]; seen {
+			// An exact repeat is harmless, anything else means we can't tell which one is right
+			if !sameResult(previous, result) && !conflicts[result.Symbol] {
+				conflicts[result.Symbol] = true
+				mismatch.Duplicate = append(mismatch.Duplicate, result.Symbol)
+			}
+			continue
+		}
+		bySymbol[result.Symbol] = result
+	}
+
+	results := make([]*time.Time, len(symbols))
+	errs := make([]error, len(symbols))
+	for i, symbol := range symbols {
+		result, found := bySymbol[symbol]
+		switch {
+		case conflicts[symbol]:
+			errs[i] = &KeyError{Symbol: symbol, Reason: "returned more than once with different results"}
+		case !found:
+			if !contains(mismatch.Missing, symbol) {
+				mismatch.Missing = append(mismatch.Missing, symbol)
+			}
+			errs[i] = &KeyError{Symbol: symbol, Reason: "missing from response"}
+		default:
+			results[i], errs[i] = result.Date, result.Err
+		}
+	}
+	return results, errs, mismatch
+}
+
+// sameResult reports whether two results for the same symbol agree.
+func sameResult(a, b DividendDate) bool {
+	switch {
+	case a.Err != nil || b.Err != nil:
+		return a.Err != nil && b.Err != nil && a.Err.Error() == b.Err.Error()
+	case a.Date == nil || b.Date == nil:
+		return a.Date == b.Date
+	default:
+		return a.Date.Equal(*b.Date)
+	}
+}
+
+// contains reports whether values contains s.
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// AlignedSource adapts a KeyedDividendDateSource to DividendDateSource, aligning every
+// response to the requested keys so one symbol's date can never be assigned to another.
+type AlignedSource struct {
+	source KeyedDividendDateSource
+}
+
+// NewAlignedSource wraps a keyed source.
+func NewAlignedSource(source KeyedDividendDateSource) *AlignedSource {
+	return &AlignedSource{source: source}
+}
+
+// FetchDividendDates implements DividendDateSource.
+func (a *AlignedSource) FetchDividendDates(ctx context.Context, symbols []string) ([]*time.Time, []error) {
+	keyed, err := a.source.FetchKeyedDividendDates(ctx, symbols)
+	if err != nil {
+		return make([]*time.Time, len(symbols)), errorsFor(len(symbols), err)
+	}
+
+	results, errs, mismatch := Align(symbols, keyed)
+	if !mismatch.Empty() {
+		keyMismatches.Add(uint64(len(mismatch.Missing) + len(mismatch.Extra) + len(mismatch.Duplicate)))
+		log.Printf("Upstream response for %v did not match the requested keys: %s (%d results)", symbols, mismatch, len(keyed))
+	}
+	return results, errs
+}
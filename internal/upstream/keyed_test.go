@@ -0,0 +1,178 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// answer is how a generated upstream response answers one requested symbol.
+type answer int
+
+const (
+	answered answer = iota
+	answeredWithError
+	missing
+	repeated     // returned twice with the same result
+	contradicted // returned twice with different results
+	numAnswers
+)
+
+// alignCase is a generated request and the keyed upstream response to it.
+type alignCase struct {
+	Symbols []string
+	Keyed   []DividendDate
+	// Answers says how each distinct requested symbol was answered.
+	Answers map[string]answer
+	// Dates are the dates answered, for symbols answered or repeated.
+	Dates map[string]time.Time
+	// Extra are the returned symbols that weren't requested.
+	Extra []string
+}
+
+// Generate implements quick.Generator: a request of up to size symbols, some requested more
+// than once, and a response answering them in an arbitrary order, with missing, repeated,
+// contradicting and unrequested results.
+func (alignCase) Generate(r *rand.Rand, size int) reflect.Value {
+	c := alignCase{Answers: make(map[string]answer), Dates: make(map[string]time.Time)}
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range r.Intn(size + 1) {
+		symbol := fmt.Sprintf("S%d", i)
+		c.Symbols = append(c.Symbols, symbol)
+		if r.Intn(5) == 0 {
+			c.Symbols = append(c.Symbols, symbol)
+		}
+
+		date := base.AddDate(0, 0, r.Intn(365))
+		c.Answers[symbol] = answer(r.Intn(int(numAnswers)))
+		switch c.Answers[symbol] {
+		case answered:
+			c.Dates[symbol] = date
+			c.Keyed = append(c.Keyed, DividendDate{Symbol: symbol, Date: &date})
+		case answeredWithError:
+			c.Keyed = append(c.Keyed, DividendDate{Symbol: symbol, Err: ErrNotFound})
+		case repeated:
+			c.Dates[symbol] = date
+			again := date
+			c.Keyed = append(c.Keyed, DividendDate{Symbol: symbol, Date: &date}, DividendDate{Symbol: symbol, Date: &again})
+		case contradicted:
+			other := date.AddDate(0, 0, 1)
+			c.Keyed = append(c.Keyed, DividendDate{Symbol: symbol, Date: &date}, DividendDate{Symbol: symbol, Date: &other})
+		}
+	}
+	for i := range r.Intn(3) {
+		symbol := fmt.Sprintf("X%d", i)
+		date := base
+		c.Extra = append(c.Extra, symbol)
+		c.Keyed = append(c.Keyed, DividendDate{Symbol: symbol, Date: &date})
+	}
+
+	r.Shuffle(len(c.Symbols), func(i, j int) { c.Symbols[i], c.Symbols[j] = c.Symbols[j], c.Symbols[i] })
+	r.Shuffle(len(c.Keyed), func(i, j int) { c.Keyed[i], c.Keyed[j] = c.Keyed[j], c.Keyed[i] })
+	return reflect.ValueOf(c)
+}
+
+// wantMismatch returns the misalignment Align should report for a case.
+func (c alignCase) wantMismatch() Misalignment {
+	var want Misalignment
+	for symbol, a := range c.Answers {
+		switch a {
+		case missing:
+			want.Missing = append(want.Missing, symbol)
+		case contradicted:
+			want.Duplicate = append(want.Duplicate, symbol)
+		}
+	}
+	want.Extra = append(want.Extra, c.Extra...)
+	return want
+}
+
+func TestAlignProperties(t *testing.T) {
+	property := func(c alignCase) bool {
+		results, errs, mismatch := Align(c.Symbols, c.Keyed)
+		if len(results) != len(c.Symbols) || len(errs) != len(c.Symbols) {
+			t.Logf("got %d results and %d errors for %d symbols", len(results), len(errs), len(c.Symbols))
+			return false
+		}
+
+		for i, symbol := range c.Symbols {
+			var keyErr *KeyError
+			switch c.Answers[symbol] {
+			case answered, repeated:
+				if errs[i] != nil || results[i] == nil || !results[i].Equal(c.Dates[symbol]) {
+					t.Logf("%s: got %v, %v, want %s", symbol, results[i], errs[i], c.Dates[symbol])
+					return false
+				}
+			case answeredWithError:
+				if !errors.Is(errs[i], ErrNotFound) || results[i] != nil {
+					t.Logf("%s: got %v, %v, want the upstream error", symbol, results[i], errs[i])
+					return false
+				}
+			case missing, contradicted:
+				if !errors.As(errs[i], &keyErr) || keyErr.Symbol != symbol || !IsRetryable(errs[i]) || results[i] != nil {
+					t.Logf("%s: got %v, %v, want a transient KeyError", symbol, results[i], errs[i])
+					return false
+				}
+			}
+		}
+
+		want := c.wantMismatch()
+		if !sameSet(mismatch.Missing, want.Missing) || !sameSet(mismatch.Duplicate, want.Duplicate) || !sameSet(mismatch.Extra, want.Extra) {
+			t.Logf("mismatch = %s, want %s", mismatch, want)
+			return false
+		}
+		return mismatch.Empty() == (len(want.Missing)+len(want.Duplicate)+len(want.Extra) == 0)
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAlignIgnoresOrder(t *testing.T) {
+	// Aligning any permutation of a response gives the same results
+	property := func(c alignCase, seed int64) bool {
+		results, errs, _ := Align(c.Symbols, c.Keyed)
+
+		shuffled := slices.Clone(c.Keyed)
+		rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		shuffledResults, shuffledErrs, _ := Align(c.Symbols, shuffled)
+
+		for i := range c.Symbols {
+			if !sameDate(results[i], shuffledResults[i]) || fmt.Sprint(errs[i]) != fmt.Sprint(shuffledErrs[i]) {
+				t.Logf("%s: %v, %v before shuffling, %v, %v after", c.Symbols[i], results[i], errs[i], shuffledResults[i], shuffledErrs[i])
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+		t.Error(err)
+	}
+}
+
+// sameSet reports whether two lists hold the same symbols, in any order.
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	sort.Strings(a)
+	sort.Strings(b)
+	return slices.Equal(a, b)
+}
+
+// sameDate reports whether two optional dates are equal.
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,125 @@
+package upstream
+
+import (
+	"context"
+	"log"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how failed keys of an upstream batch are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of calls per key, including the first.
+	// Values of 1 or less disable retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries, jitter included.
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after every retry.
+	Multiplier float64
+	// Jitter randomises each backoff by up to this fraction in either direction (0 to 1).
+	Jitter float64
+	// Retryable classifies errors worth retrying. Nil uses IsRetryable.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Retry calls fetch for symbols and retries only the keys that failed with a retryable
+// error, until they succeed, the attempts run out, or the next backoff would overrun
+// the context deadline. Results and errors are returned in the order of symbols.
+// A call returning fewer or more results or errors than keys fails all of its keys with
+// ErrMalformedResponse, which isn't retried.
+func Retry[K, V any](ctx context.Context, p RetryPolicy, symbols []K, fetch func(context.Context, []K) ([]V, []error)) ([]V, []error) {
+	results := make([]V, len(symbols))
+	errors := make([]error, len(symbols))
+
+	// pending holds the indexes into symbols still to be fetched
+	pending := make([]int, len(symbols))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	for attempt := 1; ; attempt++ {
+		keys := make([]K, len(pending))
+		for j, idx := range pending {
+			keys[j] = symbols[idx]
+		}
+
+		batchResults, batchErrors := fetch(ctx, keys)
+		lengthErr := CheckResponse(len(batchResults), len(batchErrors), len(keys))
+		if lengthErr != nil {
+			log.Print(lengthErr)
+		}
+
+		var failed []int
+		for j, idx := range pending {
+			if lengthErr != nil {
+				var zero V
+				results[idx], errors[idx] = zero, lengthErr
+			} else {
+				results[idx], errors[idx] = batchResults[j], batchErrors[j]
+			}
+			if errors[idx] != nil && p.retryable(errors[idx]) {
+				failed = append(failed, idx)
+			}
+		}
+
+		if len(failed) == 0 || attempt >= p.MaxAttempts {
+			return results, errors
+		}
+
+		backoff := p.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			log.Printf("Not retrying %d failed keys, backoff %s exceeds the request deadline", len(failed), backoff)
+			return results, errors
+		}
+
+		log.Printf("Retrying %d of %d keys in %s (attempt %d/%d)", len(failed), len(symbols), backoff, attempt+1, p.MaxAttempts)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return results, errors
+		}
+		pending = failed
+	}
+}
+
+// retryable applies the configured classification.
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// backoff returns the jittered wait after the given attempt, never more than MaxBackoff.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += (rand.Float64()*2 - 1) * p.Jitter * d
+	}
+	// Jitter must not push the wait past the cap
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	return time.Duration(d)
+}
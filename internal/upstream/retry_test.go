@@ -0,0 +1,289 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// scriptedFetch answers each call from a script of per-key errors, keyed by attempt number.
+// Keys without a scripted error for an attempt succeed with their own value.
+type scriptedFetch struct {
+	errs  map[string][]error
+	calls [][]string
+}
+
+func (f *scriptedFetch) fetch(_ context.Context, keys []string) ([]string, []error) {
+	f.calls = append(f.calls, keys)
+	results := make([]string, len(keys))
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		attempts := 0
+		for _, call := range f.calls {
+			for _, k := range call {
+				if k == key {
+					attempts++
+				}
+			}
+		}
+		if script := f.errs[key]; attempts <= len(script) && script[attempts-1] != nil {
+			errs[i] = script[attempts-1]
+			continue
+		}
+		results[i] = key
+	}
+	return results, errs
+}
+
+func TestRetry(t *testing.T) {
+	transient := fmt.Errorf("timeout: %w", ErrTransient)
+	permanent := errors.New("unknown symbol")
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 2}
+
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		errs      map[string][]error
+		wantCalls [][]string
+		wantErrs  map[string]error
+	}{
+		{
+			name:      "no errors",
+			policy:    policy,
+			wantCalls: [][]string{{"AAPL", "MSFT"}},
+		},
+		{
+			name:      "retryable key is retried alone",
+			policy:    policy,
+			errs:      map[string][]error{"MSFT": {transient}},
+			wantCalls: [][]string{{"AAPL", "MSFT"}, {"MSFT"}},
+		},
+		{
+			name:      "non-retryable key is not retried",
+			policy:    policy,
+			errs:      map[string][]error{"MSFT": {permanent}},
+			wantCalls: [][]string{{"AAPL", "MSFT"}},
+			wantErrs:  map[string]error{"MSFT": permanent},
+		},
+		{
+			name:      "deadline errors are not retried",
+			policy:    policy,
+			errs:      map[string][]error{"AAPL": {context.DeadlineExceeded}},
+			wantCalls: [][]string{{"AAPL", "MSFT"}},
+			wantErrs:  map[string]error{"AAPL": context.DeadlineExceeded},
+		},
+		{
+			name:      "attempts are capped",
+			policy:    policy,
+			errs:      map[string][]error{"AAPL": {transient, transient, transient, transient}},
+			wantCalls: [][]string{{"AAPL", "MSFT"}, {"AAPL"}, {"AAPL"}},
+			wantErrs:  map[string]error{"AAPL": transient},
+		},
+		{
+			name:      "single attempt disables retries",
+			policy:    RetryPolicy{MaxAttempts: 1},
+			errs:      map[string][]error{"AAPL": {transient}},
+			wantCalls: [][]string{{"AAPL", "MSFT"}},
+			wantErrs:  map[string]error{"AAPL": transient},
+		},
+		{
+			name:      "custom classification",
+			policy:    RetryPolicy{MaxAttempts: 2, Retryable: func(err error) bool { return errors.Is(err, permanent) }},
+			errs:      map[string][]error{"AAPL": {transient}, "MSFT": {permanent}},
+			wantCalls: [][]string{{"AAPL", "MSFT"}, {"MSFT"}},
+			wantErrs:  map[string]error{"AAPL": transient},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &scriptedFetch{errs: tt.errs}
+			symbols := []string{"AAPL", "MSFT"}
+			results, errs := Retry(context.Background(), tt.policy, symbols, f.fetch)
+
+			if fmt.Sprint(f.calls) != fmt.Sprint(tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", f.calls, tt.wantCalls)
+			}
+			for i, symbol := range symbols {
+				want := tt.wantErrs[symbol]
+				if !errors.Is(errs[i], want) || (want == nil && errs[i] != nil) {
+					t.Errorf("error for %s = %v, want %v", symbol, errs[i], want)
+				}
+				if want == nil && results[i] != symbol {
+					t.Errorf("result for %s = %q, want %q", symbol, results[i], symbol)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryShortResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		results int
+		errs    int
+	}{
+		{name: "short results", results: 1, errs: 2},
+		{name: "short errors", results: 2, errs: 0},
+		{name: "extra results", results: 3, errs: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			fetch := func(_ context.Context, keys []string) ([]string, []error) {
+				calls++
+				return make([]string, tt.results), make([]error, tt.errs)
+			}
+			policy := RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+			results, errs := Retry(context.Background(), policy, []string{"AAPL", "MSFT"}, fetch)
+
+			// A malformed response isn't retried
+			if calls != 1 {
+				t.Errorf("calls = %d, want 1", calls)
+			}
+			if len(results) != 2 || len(errs) != 2 {
+				t.Fatalf("got %d results and %d errors, want 2 of each", len(results), len(errs))
+			}
+			for i, err := range errs {
+				if !errors.Is(err, ErrMalformedResponse) || IsRetryable(err) {
+					t.Errorf("error %d = %v, want a non-retryable malformed response error", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	transient := fmt.Errorf("timeout: %w", ErrTransient)
+	failing := func(_ context.Context, keys []string) ([]string, []error) {
+		return make([]string, len(keys)), errorsFor(len(keys), transient)
+	}
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+	}{
+		{
+			name: "cancelled during backoff",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(10*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+		},
+		{
+			name: "backoff overruns the deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), time.Minute)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			calls := 0
+			done := make(chan []error, 1)
+			go func() {
+				_, errs := Retry(ctx, policy, []string{"AAPL"}, func(ctx context.Context, keys []string) ([]string, []error) {
+					calls++
+					return failing(ctx, keys)
+				})
+				done <- errs
+			}()
+
+			select {
+			case errs := <-done:
+				if calls != 1 {
+					t.Errorf("calls = %d, want 1", calls)
+				}
+				if !errors.Is(errs[0], transient) {
+					t.Errorf("error = %v, want the last upstream error", errs[0])
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Retry didn't return")
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  int
+		min, max time.Duration
+	}{
+		{
+			name:    "first retry waits the initial backoff",
+			policy:  RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2},
+			attempt: 1,
+			min:     100 * time.Millisecond,
+			max:     100 * time.Millisecond,
+		},
+		{
+			name:    "backoff grows by the multiplier",
+			policy:  RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2},
+			attempt: 3,
+			min:     400 * time.Millisecond,
+			max:     400 * time.Millisecond,
+		},
+		{
+			name:    "backoff is capped",
+			policy:  RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 10},
+			attempt: 5,
+			min:     time.Second,
+			max:     time.Second,
+		},
+		{
+			name:    "multipliers below one keep the initial backoff",
+			policy:  RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 0.5},
+			attempt: 4,
+			min:     100 * time.Millisecond,
+			max:     100 * time.Millisecond,
+		},
+		{
+			name:    "jitter stays within its fraction",
+			policy:  RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.2},
+			attempt: 2,
+			min:     160 * time.Millisecond,
+			max:     240 * time.Millisecond,
+		},
+		{
+			name:    "jitter doesn't exceed the cap",
+			policy:  RetryPolicy{InitialBackoff: time.Second, MaxBackoff: time.Second, Multiplier: 2, Jitter: 0.5},
+			attempt: 3,
+			min:     500 * time.Millisecond,
+			max:     time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 100 {
+				if got := tt.policy.backoff(tt.attempt); got < tt.min || got > tt.max {
+					t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoffNeverExceedsMax(t *testing.T) {
+	for _, jitter := range []float64{0, 0.2, 0.5, 1} {
+		policy := RetryPolicy{InitialBackoff: 300 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2, Jitter: jitter}
+		for attempt := 1; attempt <= 10; attempt++ {
+			for range 100 {
+				if got := policy.backoff(attempt); got > policy.MaxBackoff {
+					t.Fatalf("jitter %v: backoff(%d) = %s, want at most %s", jitter, attempt, got, policy.MaxBackoff)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package upstream
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// SimulatedSource simulates a batch dividend date API with a fixed latency
+// and deterministic dates for demo purposes.
+type SimulatedSource struct {
+	// Latency is how long every batch call takes.
+	Latency time.Duration
+	// Known reports whether the upstream has data for a symbol. Other symbols fail with
+	// ErrNotFound. Nil knows every symbol.
+	Known func(symbol string) bool
+}
+
+// NewSimulatedSource creates a simulated source with the default 500ms latency.
+func NewSimulatedSource() *SimulatedSource {
+	return &SimulatedSource{Latency: 500 * time.Millisecond}
+}
+
+// FetchKeyedDividendDates implements KeyedDividendDateSource. Like many real batch APIs,
+// it answers with a result per symbol in no particular order.
+func (s *SimulatedSource) FetchKeyedDividendDates(ctx context.Context, symbols []string) ([]DividendDate, error) {
+	log.Printf("Calling simulated API for keys: %v", symbols)
+
+	// Simulate API latency, giving up if the caller does
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	dates := make(map[string]time.Time, len(symbols))
+	var unknown []string
+	for _, name := range symbols {
+		if !s.known(name) {
+			unknown = append(unknown, name)
+			continue
+		}
+		log.Printf("Simulating API fetch for %s", name)
+		dates[name] = time.Now().AddDate(0, simulatedProfile(name).monthsAhead, 0)
+	}
+
+	results := make([]DividendDate, 0, len(symbols))
+	for name, date := range dates {
+		results = append(results, DividendDate{Symbol: name, Date: &date})
+	}
+	for _, name := range unknown {
+		results = append(results, DividendDate{Symbol: name, Err: ErrNotFound})
+	}
+	return results, nil
+}
+
+// simulatedHistoryYears is how far back the simulated dividend history goes.
+const simulatedHistoryYears = 10
+
+// profile is the deterministic dividend policy simulated for a symbol.
+type profile struct {
+	// monthsAhead is when the next ex-dividend date is, from now.
+	monthsAhead int
+	amount      float64
+	yield       float64
+	frequency   Frequency
+}
+
+// simulatedProfile returns the demo dividend policy of a symbol.
+func simulatedProfile(name string) profile {
+	switch name {
+	case "AAPL":
+		return profile{monthsAhead: 1, amount: 0.25, yield: 0.0044, frequency: FrequencyQuarterly}
+	case "MSFT":
+		return profile{monthsAhead: 2, amount: 0.83, yield: 0.0072, frequency: FrequencyQuarterly}
+	case "GOOG":
+		return profile{monthsAhead: 3, amount: 0.20, yield: 0.0045, frequency: FrequencyQuarterly}
+	default:
+		return profile{monthsAhead: 6, amount: 0.50, yield: 0.02, frequency: FrequencyQuarterly}
+	}
+}
+
+// dividendOn builds the simulated dividend going ex on exDate.
+func (p profile) dividendOn(exDate time.Time) Dividend {
+	record := exDate.AddDate(0, 0, 1)
+	pay := exDate.AddDate(0, 0, 14)
+	declared := exDate.AddDate(0, 0, -30)
+	yield := p.yield
+	return Dividend{
+		Amount:          p.amount,
+		Currency:        "USD",
+		ExDate:          exDate,
+		RecordDate:      &record,
+		PayDate:         &pay,
+		DeclarationDate: &declared,
+		Frequency:       p.frequency,
+		Yield:           &yield,
+	}
+}
+
+// wait simulates the API latency, returning the context error if the caller gives up first.
+func (s *SimulatedSource) wait(ctx context.Context) error {
+	select {
+	case <-time.After(s.Latency):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// known reports whether the simulated upstream has data for a symbol.
+func (s *SimulatedSource) known(name string) bool {
+	return s.Known == nil || s.Known(name)
+}
+
+// today returns the current day at midnight UTC, so simulated dividend dates are stable
+// across calls made on the same day.
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// FetchNextDividends implements DividendSource.
+func (s *SimulatedSource) FetchNextDividends(ctx context.Context, symbols []string) ([]*Dividend, []error) {
+	log.Printf("Calling simulated next dividend API for keys: %v", symbols)
+	if err := s.wait(ctx); err != nil {
+		return make([]*Dividend, len(symbols)), errorsFor(len(symbols), err)
+	}
+
+	results := make([]*Dividend, len(symbols))
+	errs := make([]error, len(symbols))
+	for i, name := range symbols {
+		if !s.known(name) {
+			errs[i] = ErrNotFound
+			continue
+		}
+		results[i] = simulatedNextDividend(name)
+	}
+	return results, errs
+}
+
+// simulatedNextDividend returns the next dividend of a symbol.
+func simulatedNextDividend(name string) *Dividend {
+	p := simulatedProfile(name)
+	dividend := p.dividendOn(today().AddDate(0, p.monthsAhead, 0))
+	return &dividend
+}
+
+// FetchDividendHistory implements DividendSource.
+func (s *SimulatedSource) FetchDividendHistory(ctx context.Context, r HistoryRange, symbols []string) ([]*HistoryPage, []error) {
+	log.Printf("Calling simulated dividend history API for keys: %v (%+v)", symbols, r)
+	if err := s.wait(ctx); err != nil {
+		return make([]*HistoryPage, len(symbols)), errorsFor(len(symbols), err)
+	}
+
+	results := make([]*HistoryPage, len(symbols))
+	errs := make([]error, len(symbols))
+	for i, name := range symbols {
+		if !s.known(name) {
+			errs[i] = ErrNotFound
+			continue
+		}
+		results[i] = r.Page(simulatedHistory(name))
+	}
+	return results, errs
+}
+
+// simulatedHistory returns the quarterly payments of a symbol going back from the last
+// one before its next ex-dividend date, oldest first.
+func simulatedHistory(name string) []Dividend {
+	p := simulatedProfile(name)
+	next := today().AddDate(0, p.monthsAhead, 0)
+	start := next.AddDate(-simulatedHistoryYears, 0, 0)
+
+	var history []Dividend
+	for exDate := start; exDate.Before(next) && !exDate.After(today()); exDate = exDate.AddDate(0, 3, 0) {
+		history = append(history, p.dividendOn(exDate))
+	}
+	return history
+}
@@ -0,0 +1,145 @@
+package upstream
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// simulatedSplits are the demo split histories, oldest first.
+var simulatedSplits = map[string][]Split{
+	"AAPL": {
+		simulatedSplit(2005, time.February, 28, 2, 1),
+		simulatedSplit(2014, time.June, 9, 7, 1),
+		simulatedSplit(2020, time.August, 31, 4, 1),
+	},
+	"MSFT": {
+		simulatedSplit(1999, time.March, 29, 2, 1),
+		simulatedSplit(2003, time.February, 18, 2, 1),
+	},
+	"GOOG": {
+		simulatedSplit(2022, time.July, 18, 20, 1),
+	},
+}
+
+// simulatedSplit builds a split announced a month before its ex date.
+func simulatedSplit(year int, month time.Month, day, numerator, denominator int) Split {
+	exDate := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	announced := exDate.AddDate(0, -1, 0)
+	return Split{ExDate: exDate, Numerator: numerator, Denominator: denominator, AnnouncementDate: &announced}
+}
+
+// FetchNextEarningsDates implements EarningsSource.
+func (s *SimulatedSource) FetchNextEarningsDates(ctx context.Context, symbols []string) ([]*time.Time, []error) {
+	log.Printf("Calling simulated earnings API for keys: %v", symbols)
+	if err := s.wait(ctx); err != nil {
+		return make([]*time.Time, len(symbols)), errorsFor(len(symbols), err)
+	}
+
+	results := make([]*time.Time, len(symbols))
+	errs := make([]error, len(symbols))
+	for i, name := range symbols {
+		if !s.known(name) {
+			errs[i] = ErrNotFound
+			continue
+		}
+		results[i] = simulatedNextEarningsDate(name)
+	}
+	return results, errs
+}
+
+// simulatedNextEarningsDate returns the next earnings date of a symbol.
+func simulatedNextEarningsDate(name string) *time.Time {
+	// Deterministic logic for demo purposes
+	days := 45
+	switch name {
+	case "AAPL":
+		days = 15
+	case "MSFT":
+		days = 20
+	case "GOOG":
+		days = 25
+	}
+	date := today().AddDate(0, 0, days)
+	return &date
+}
+
+// FetchNextSplits implements SplitSource. Only MSFT has an announced split.
+func (s *SimulatedSource) FetchNextSplits(ctx context.Context, symbols []string) ([]*Split, []error) {
+	log.Printf("Calling simulated next split API for keys: %v", symbols)
+	if err := s.wait(ctx); err != nil {
+		return make([]*Split, len(symbols)), errorsFor(len(symbols), err)
+	}
+
+	results := make([]*Split, len(symbols))
+	errs := make([]error, len(symbols))
+	for i, name := range symbols {
+		if !s.known(name) {
+			errs[i] = ErrNotFound
+			continue
+		}
+		results[i] = simulatedNextSplit(name)
+	}
+	return results, errs
+}
+
+// simulatedNextSplit returns the next announced split of a symbol, if any.
+func simulatedNextSplit(name string) *Split {
+	if name != "MSFT" {
+		return nil
+	}
+	next := today().AddDate(0, 3, 0)
+	split := simulatedSplit(next.Year(), next.Month(), next.Day(), 2, 1)
+	return &split
+}
+
+// FetchSplits implements SplitSource.
+func (s *SimulatedSource) FetchSplits(ctx context.Context, symbols []string) ([][]Split, []error) {
+	log.Printf("Calling simulated split history API for keys: %v", symbols)
+	if err := s.wait(ctx); err != nil {
+		return make([][]Split, len(symbols)), errorsFor(len(symbols), err)
+	}
+
+	results := make([][]Split, len(symbols))
+	errs := make([]error, len(symbols))
+	for i, name := range symbols {
+		if !s.known(name) {
+			errs[i] = ErrNotFound
+			continue
+		}
+		results[i] = simulatedSplits[name]
+	}
+	return results, errs
+}
+
+// FetchCorporateActions implements CorporateActionsSource, answering every field in one call.
+func (s *SimulatedSource) FetchCorporateActions(ctx context.Context, symbols []string, fields []ActionField) ([]*CorporateActions, []error) {
+	log.Printf("Calling simulated corporate actions API for keys: %v (fields %v)", symbols, fields)
+	if err := s.wait(ctx); err != nil {
+		return make([]*CorporateActions, len(symbols)), errorsFor(len(symbols), err)
+	}
+
+	results := make([]*CorporateActions, len(symbols))
+	errs := make([]error, len(symbols))
+	for i, name := range symbols {
+		if !s.known(name) {
+			errs[i] = ErrNotFound
+			continue
+		}
+		actions := &CorporateActions{}
+		for _, field := range fields {
+			switch field {
+			case ActionNextDividend:
+				actions.NextDividend = simulatedNextDividend(name)
+			case ActionNextEarningsDate:
+				actions.NextEarningsDate = simulatedNextEarningsDate(name)
+			case ActionNextSplit:
+				actions.NextSplit = simulatedNextSplit(name)
+			case ActionSplits:
+				actions.Splits = simulatedSplits[name]
+			}
+		}
+		results[i] = actions
+	}
+	return results, errs
+}
@@ -0,0 +1,53 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DividendDateSource fetches next ex-dividend dates for a batch of symbols from an upstream API.
+// Implementations return one result and one error per requested symbol, in request order.
+// Upstreams that answer keyed by symbol are adapted with NewAlignedSource.
+type DividendDateSource interface {
+	FetchDividendDates(ctx context.Context, symbols []string) ([]*time.Time, []error)
+}
+
+// ErrTransient marks upstream failures that are worth retrying, such as timeouts,
+// dropped connections or 5xx responses. Sources wrap it with fmt.Errorf("...: %w", ErrTransient).
+var ErrTransient = errors.New("transient upstream error")
+
+// ErrNotFound is the per-key error for symbols the upstream doesn't know. It isn't retried.
+var ErrNotFound = errors.New("symbol not found")
+
+// ErrMalformedResponse is the error for every key of a call whose response doesn't hold one
+// result and one error per key. It isn't retried, but it counts as an upstream failure.
+var ErrMalformedResponse = errors.New("malformed upstream response")
+
+// CheckResponse returns an ErrMalformedResponse unless a response to keys holds as many
+// results and errors as there are keys.
+func CheckResponse(results, errs, keys int) error {
+	if results == keys && errs == keys {
+		return nil
+	}
+	return fmt.Errorf("upstream returned %d results and %d errors for %d keys: %w", results, errs, keys, ErrMalformedResponse)
+}
+
+// IsRetryable is the default retry classification: transient upstream errors are retried,
+// while cancellations, deadlines and every other error are returned as is.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return errors.Is(err, ErrTransient)
+}
+
+// errorsFor returns a slice with err repeated for each of n keys.
+func errorsFor(n int, err error) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}